@@ -0,0 +1,39 @@
+package main
+
+import "io"
+
+// ansiStripper removes ANSI escape sequences (ESC [ ... final byte) from
+// everything written through it, for terminals that cannot display them.
+// Sequences split across writes are handled.
+type ansiStripper struct {
+	w     io.Writer
+	state int // 0 text, 1 after ESC, 2 inside CSI
+}
+
+func (s *ansiStripper) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p))
+	for _, c := range p {
+		switch s.state {
+		case 0:
+			if c == 0x1b {
+				s.state = 1
+				continue
+			}
+			out = append(out, c)
+		case 1:
+			if c == '[' {
+				s.state = 2
+			} else {
+				s.state = 0
+			}
+		case 2:
+			if c >= 0x40 && c <= 0x7e {
+				s.state = 0
+			}
+		}
+	}
+	if _, err := s.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errAssertionFailed is returned when the response did not meet every
+// -expect-header rule.
+var errAssertionFailed = errors.New("response assertions failed")
+
+// stringList is a flag that may be given several times.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// headerRule is a parsed -expect-header value. Supported forms:
+//
+//	Name              the header is present
+//	!Name             the header is absent
+//	Name: value       some value equals value exactly
+//	Name: ~regexp     some value matches the regular expression
+//	Name: >10         the value compares numerically (>, >=, <, <=, ==, !=)
+//	Name: #2, #>=2    the header occurs that many times
+type headerRule struct {
+	text   string
+	name   string
+	absent bool
+	count  bool
+	op     string
+	num    float64
+	value  string
+	re     *regexp.Regexp
+}
+
+// assertionResult is the outcome of one rule, as shown in the output.
+type assertionResult struct {
+	Expect string `json:"expect"`
+	Passed bool   `json:"passed"`
+	Actual string `json:"actual,omitempty"`
+}
+
+var comparisonOps = []string{">=", "<=", "==", "!=", ">", "<"}
+
+func parseHeaderRule(text string) (*headerRule, error) {
+	rule := &headerRule{text: text}
+	name, expr, hasExpr := strings.Cut(text, ":")
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "!") {
+		if hasExpr {
+			return nil, fmt.Errorf("%q: an absence check (!Name) takes no value", text)
+		}
+		rule.absent = true
+		name = strings.TrimSpace(name[1:])
+	}
+	if !isToken(name) {
+		return nil, fmt.Errorf("%q: %q is not a valid header name", text, name)
+	}
+	rule.name = http.CanonicalHeaderKey(name)
+
+	expr = strings.TrimSpace(expr)
+	if !hasExpr || expr == "" {
+		return rule, nil
+	}
+
+	if strings.HasPrefix(expr, "#") {
+		rule.count = true
+		expr = strings.TrimSpace(expr[1:])
+		if _, err := strconv.Atoi(expr); err == nil {
+			expr = "==" + expr
+		}
+	}
+	if strings.HasPrefix(expr, "~") {
+		re, err := regexp.Compile(expr[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", text, err)
+		}
+		rule.re = re
+		return rule, nil
+	}
+	for _, op := range comparisonOps {
+		rest, ok := strings.CutPrefix(expr, op)
+		if !ok {
+			continue
+		}
+		// values such as <https://x/2>; rel="next" start like a comparison
+		// but are matched exactly
+		num, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+		if err != nil {
+			if rule.count {
+				return nil, fmt.Errorf("%q: %q is not a number", text, strings.TrimSpace(rest))
+			}
+			break
+		}
+		rule.op, rule.num = op, num
+		return rule, nil
+	}
+	if rule.count {
+		return nil, fmt.Errorf("%q: a count check needs a number, e.g. #2 or #>=1", text)
+	}
+	rule.value = expr
+	return rule, nil
+}
+
+// check evaluates the rule against the response headers.
+func (r *headerRule) check(h http.Header) assertionResult {
+	values := h.Values(r.name)
+	result := assertionResult{Expect: r.text, Actual: strings.Join(values, ", ")}
+
+	switch {
+	case r.absent:
+		result.Passed = len(values) == 0
+	case r.count:
+		result.Actual = fmt.Sprintf("%d occurrence(s)", len(values))
+		result.Passed = compare(float64(len(values)), r.op, r.num)
+	case len(values) == 0:
+		result.Actual = "(missing)"
+	case r.re != nil:
+		for _, v := range values {
+			if r.re.MatchString(v) {
+				result.Passed = true
+			}
+		}
+	case r.op != "":
+		n, err := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+		result.Passed = err == nil && compare(n, r.op, r.num)
+	case r.value != "":
+		for _, v := range values {
+			if v == r.value {
+				result.Passed = true
+			}
+		}
+	default:
+		result.Passed = true
+	}
+	return result
+}
+
+func compare(a float64, op string, b float64) bool {
+	switch op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	}
+	return false
+}
+
+// checkAssertions evaluates every rule and reports the results along with
+// an error naming the failed ones.
+func checkAssertions(rules []*headerRule, h http.Header) ([]assertionResult, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	results := make([]assertionResult, len(rules))
+	var failed []string
+	for i, rule := range rules {
+		results[i] = rule.check(h)
+		if !results[i].Passed {
+			failed = append(failed, rule.text)
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%w: %s", errAssertionFailed, strings.Join(failed, "; "))
+	}
+	return results, nil
+}
+
+func printAssertions(w io.Writer, loc *localizer, results []assertionResult) {
+	if len(results) == 0 {
+		return
+	}
+	fmt.Fprintln(w, loc.T("Assertions:"))
+	for _, r := range results {
+		mark := "PASS"
+		if !r.Passed {
+			mark = "FAIL"
+		}
+		if r.Actual != "" {
+			fmt.Fprintf(w, "  %s  %s  (%s)\n", mark, r.Expect, r.Actual)
+		} else {
+			fmt.Fprintf(w, "  %s  %s\n", mark, r.Expect)
+		}
+	}
+}
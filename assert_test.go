@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseHeaderRule(t *testing.T) {
+	tests := []struct {
+		text string
+		err  bool
+	}{
+		{`Content-Type`, false},
+		{`!Set-Cookie`, false},
+		{`Content-Type: application/json`, false},
+		{`Content-Type: ~^application/`, false},
+		{`X-RateLimit-Remaining: >0`, false},
+		{`Set-Cookie: #2`, false},
+		{`Set-Cookie: #>=2`, false},
+		{`Link: <https://x/2>; rel="next"`, false},
+		{`!Set-Cookie: a=1`, true},
+		{`Bad Name: 1`, true},
+		{`Content-Type: ~(`, true},
+		{`Set-Cookie: #many`, true},
+		{`Set-Cookie: #>=x`, true},
+	}
+	for _, tt := range tests {
+		_, err := parseHeaderRule(tt.text)
+		if (err != nil) != tt.err {
+			t.Errorf("parseHeaderRule(%q) error = %v, want error %v", tt.text, err, tt.err)
+		}
+	}
+}
+
+func TestHeaderRuleCheck(t *testing.T) {
+	h := http.Header{
+		"Content-Type":          {"application/json; charset=utf-8"},
+		"X-Ratelimit-Remaining": {"5"},
+		"Set-Cookie":            {"a=1", "b=2"},
+		"Link":                  {`<https://x/2>; rel="next"`},
+		"Retry-After":           {">5"},
+	}
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{`Content-Type`, true},
+		{`ETag`, false},
+		{`!ETag`, true},
+		{`!Set-Cookie`, false},
+		{`Content-Type: application/json; charset=utf-8`, true},
+		{`Content-Type: application/json`, false},
+		{`Set-Cookie: b=2`, true},
+		{`Content-Type: ~^application/json`, true},
+		{`Content-Type: ~^text/`, false},
+		{`x-ratelimit-remaining: >0`, true},
+		{`X-RateLimit-Remaining: >=6`, false},
+		{`X-RateLimit-Remaining: ==5`, true},
+		{`X-RateLimit-Remaining: !=5`, false},
+		{`Content-Type: >0`, false},
+		{`ETag: >0`, false},
+		{`Set-Cookie: #2`, true},
+		{`Set-Cookie: #1`, false},
+		{`Set-Cookie: #>=2`, true},
+		{`ETag: #0`, true},
+		{`Link: <https://x/2>; rel="next"`, true},
+		{`Link: <https://x/3>; rel="next"`, false},
+		{`Retry-After: >5`, false},
+	}
+	for _, tt := range tests {
+		rule, err := parseHeaderRule(tt.text)
+		if err != nil {
+			t.Fatalf("parseHeaderRule(%q): %v", tt.text, err)
+		}
+		if got := rule.check(h); got.Passed != tt.want {
+			t.Errorf("%q passed = %v (actual %q), want %v", tt.text, got.Passed, got.Actual, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditRecord is one line of the audit log.
+type auditRecord struct {
+	Time       string  `json:"time"`
+	User       string  `json:"user"`
+	Method     string  `json:"method"`
+	URL        string  `json:"url"`
+	Status     int     `json:"status,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+	Bytes      int64   `json:"bytes"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// auditTransport appends a JSON line to the audit log for every round trip,
+// including redirects and retries. Records are written once the response
+// body has been consumed so the byte count is known.
+type auditTransport struct {
+	next http.RoundTripper
+	user string
+
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// newAuditTransport opens (or creates) the log at path for appending and
+// wraps next, which may be nil for the default transport.
+func newAuditTransport(next http.RoundTripper, path string) (*auditTransport, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return &auditTransport{next: next, user: name, w: f}, nil
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	record := auditRecord{
+		Time:   time.Now().UTC().Format(time.RFC3339Nano),
+		User:   t.user,
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		record.DurationMs = durationMs(time.Since(start))
+		record.Error = err.Error()
+		t.write(record)
+		return nil, err
+	}
+	record.Status = resp.StatusCode
+	resp.Body = &auditBody{ReadCloser: resp.Body, done: func(n int64) {
+		record.DurationMs = durationMs(time.Since(start))
+		record.Bytes = n
+		t.write(record)
+	}}
+	return resp, nil
+}
+
+// Close closes the log file.
+func (t *auditTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.w.Close()
+}
+
+func (t *auditTransport) write(record auditRecord) {
+	var line strings.Builder
+	enc := json.NewEncoder(&line)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(record); err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	io.WriteString(t.w, line.String())
+}
+
+// auditBody counts the bytes read from a response body and reports the
+// total once, when the body is closed.
+type auditBody struct {
+	io.ReadCloser
+	n    int64
+	once sync.Once
+	done func(int64)
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *auditBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.n) })
+	return err
+}
+
+// sensitiveParams are query parameter name fragments whose values are
+// never written to the audit log.
+var sensitiveParams = []string{"token", "key", "secret", "password", "passwd", "auth", "signature", "sig", "code", "session"}
+
+// redactURL renders u with credentials and sensitive query values replaced.
+func redactURL(u *url.URL) string {
+	clean := *u
+	if clean.User != nil {
+		clean.User = url.User(clean.User.Username())
+		if _, ok := u.User.Password(); ok {
+			clean.User = url.UserPassword(clean.User.Username(), "REDACTED")
+		}
+	}
+	query := clean.Query()
+	changed := false
+	for name, values := range query {
+		lower := strings.ToLower(name)
+		for _, s := range sensitiveParams {
+			if strings.Contains(lower, s) {
+				for i := range values {
+					values[i] = "REDACTED"
+				}
+				changed = true
+				break
+			}
+		}
+	}
+	if changed {
+		clean.RawQuery = query.Encode()
+	}
+	return clean.String()
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
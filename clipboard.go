@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// copyToClipboard places data on the system clipboard using the platform's
+// clipboard utility.
+func copyToClipboard(data []byte) error {
+	name, args, err := clipboardCommand()
+	if err != nil {
+		return err
+	}
+	// xclip and wl-copy stay in the background to serve the clipboard, still
+	// holding stderr, so stop waiting for it shortly after they return
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = 200 * time.Millisecond
+	if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		return fmt.Errorf("%s: %v %s", name, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+func clipboardCommand() (string, []string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "pbcopy", nil, nil
+	case "windows":
+		return "clip.exe", nil, nil
+	}
+
+	// Linux and the BSDs: prefer Wayland, then the X11 tools
+	candidates := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append([][]string{{"wl-copy"}}, candidates...)
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c[0]); err == nil {
+			return c[0], c[1:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("no clipboard utility found (install wl-copy, xclip or xsel)")
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// condition is a parsed -retry-if expression. It supports
+//
+//	status, body, body.a.b.0, header.Name   values from the response
+//	"text", 'text', 42, true, false, null   literals
+//	== != < <= > >=                         comparisons
+//	=~                                      regular expression match
+//	! && || ( )                             logic
+//
+// A value on its own is true unless it is missing, false, 0 or "".
+type condition struct {
+	text string
+	root condNode
+}
+
+// condNode evaluates one part of an expression against a response.
+type condNode func(env *condEnv) interface{}
+
+// condEnv is the response an expression is evaluated against. The body is
+// decoded at most once.
+type condEnv struct {
+	resp    *response
+	doc     interface{}
+	decoded bool
+}
+
+func (e *condEnv) body() interface{} {
+	if !e.decoded {
+		e.decoded = true
+		if err := json.Unmarshal(e.resp.body, &e.doc); err != nil {
+			e.doc = string(e.resp.body)
+		}
+	}
+	return e.doc
+}
+
+// match reports whether the expression holds for resp.
+func (c *condition) match(resp *response) bool {
+	return truthy(c.root(&condEnv{resp: resp}))
+}
+
+func parseCondition(text string) (*condition, error) {
+	toks, err := lexCondition(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &condParser{toks: toks}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	return &condition{text: text, root: root}, nil
+}
+
+type condToken struct {
+	kind byte // 'o' operator, 'i' identifier, 'n' number, 's' string
+	text string
+	num  float64
+}
+
+var condOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")"}
+
+func lexCondition(s string) ([]condToken, error) {
+	var toks []condToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+			continue
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(s) && s[end] != c {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string starting at %q", s[i:])
+			}
+			raw := s[i : end+1]
+			if c == '\'' {
+				raw = `"` + strings.ReplaceAll(raw[1:len(raw)-1], `"`, `\"`) + `"`
+			}
+			text, err := strconv.Unquote(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", s[i:end+1])
+			}
+			toks = append(toks, condToken{kind: 's', text: text})
+			i = end + 1
+			continue
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			end := i + 1
+			for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] == '.') {
+				end++
+			}
+			n, err := strconv.ParseFloat(s[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", s[i:end])
+			}
+			toks = append(toks, condToken{kind: 'n', text: s[i:end], num: n})
+			i = end
+			continue
+		case unicode.IsLetter(rune(c)) || c == '_':
+			// paths may contain dots, digits and the dashes of header names
+			end := i + 1
+			for end < len(s) && (unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end])) || strings.IndexByte("_.-", s[end]) >= 0) {
+				end++
+			}
+			toks = append(toks, condToken{kind: 'i', text: s[i:end]})
+			i = end
+			continue
+		}
+		matched := false
+		for _, op := range condOperators {
+			if strings.HasPrefix(s[i:], op) {
+				toks = append(toks, condToken{kind: 'o', text: op})
+				i += len(op)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("unexpected %q", s[i:i+1])
+		}
+	}
+	return toks, nil
+}
+
+// condParser is a recursive descent parser over the tokens, from the
+// loosest binding operator (||) to the tightest (operands).
+type condParser struct {
+	toks []condToken
+	pos  int
+}
+
+func (p *condParser) accept(op string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].kind == 'o' && p.toks[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *condParser) or() (condNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(env *condEnv) interface{} { return truthy(l(env)) || truthy(right(env)) }
+	}
+	return left, nil
+}
+
+func (p *condParser) and() (condNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(env *condEnv) interface{} { return truthy(l(env)) && truthy(right(env)) }
+	}
+	return left, nil
+}
+
+func (p *condParser) unary() (condNode, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env *condEnv) interface{} { return !truthy(operand(env)) }, nil
+	}
+	return p.comparison()
+}
+
+func (p *condParser) comparison() (condNode, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "=~"} {
+		if !p.accept(op) {
+			continue
+		}
+		if op == "=~" {
+			if p.pos >= len(p.toks) || p.toks[p.pos].kind != 's' {
+				return nil, fmt.Errorf("=~ needs a quoted regular expression")
+			}
+			re, err := regexp.Compile(p.toks[p.pos].text)
+			if err != nil {
+				return nil, err
+			}
+			p.pos++
+			return func(env *condEnv) interface{} {
+				v := left(env)
+				return v != nil && re.MatchString(jsonText(v))
+			}, nil
+		}
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(env *condEnv) interface{} { return compareValues(left(env), op, right(env)) }, nil
+	}
+	return left, nil
+}
+
+func (p *condParser) operand() (condNode, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	if p.accept("(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+
+	tok := p.toks[p.pos]
+	p.pos++
+	switch tok.kind {
+	case 'n':
+		return func(*condEnv) interface{} { return tok.num }, nil
+	case 's':
+		return func(*condEnv) interface{} { return tok.text }, nil
+	case 'i':
+		return identifier(tok.text)
+	}
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+// identifier resolves a name to the literal or response value it stands for.
+func identifier(name string) (condNode, error) {
+	switch name {
+	case "true":
+		return func(*condEnv) interface{} { return true }, nil
+	case "false":
+		return func(*condEnv) interface{} { return false }, nil
+	case "null":
+		return func(*condEnv) interface{} { return nil }, nil
+	case "status":
+		return func(env *condEnv) interface{} { return float64(env.resp.StatusCode) }, nil
+	case "body":
+		return func(env *condEnv) interface{} { return env.body() }, nil
+	}
+	if path, ok := strings.CutPrefix(name, "body."); ok {
+		return func(env *condEnv) interface{} {
+			// a missing field, or a body that is not JSON, is null
+			v, err := walkJSON(env.body(), path)
+			if err != nil {
+				return nil
+			}
+			return v
+		}, nil
+	}
+	if header, ok := strings.CutPrefix(name, "header."); ok {
+		return func(env *condEnv) interface{} {
+			if values := env.resp.Header.Values(header); len(values) > 0 {
+				return values[0]
+			}
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown name %q; use status, body, body.<path> or header.<Name>", name)
+}
+
+// compareValues compares two values, numerically when either is a number
+// and the other is a number or a numeric string, such as a header value.
+func compareValues(a interface{}, op string, b interface{}) bool {
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		if x, ok := number(a); ok {
+			if y, ok := number(b); ok {
+				return compare(x, op, y)
+			}
+		}
+	}
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			switch op {
+			case "==":
+				return x == y
+			case "!=":
+				return x != y
+			case "<":
+				return x < y
+			case "<=":
+				return x <= y
+			case ">":
+				return x > y
+			case ">=":
+				return x >= y
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return jsonText(a) == jsonText(b) && (a == nil) == (b == nil)
+	case "!=":
+		return jsonText(a) != jsonText(b) || (a == nil) != (b == nil)
+	}
+	return false
+}
+
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		text string
+		err  bool
+	}{
+		{`status == 409`, false},
+		{`status >= 500 || header.Retry-After`, false},
+		{`!(body.state == "done") && body.items.0.id != null`, false},
+		{`body.name =~ '^a.*'`, false},
+		{`-1 < status`, false},
+		{``, true},
+		{`status ==`, true},
+		{`(status == 200`, true},
+		{`status == 200)`, true},
+		{`body.name =~ name`, true},
+		{`body.name =~ "("`, true},
+		{`"unterminated`, true},
+		{`method == "GET"`, true},
+		{`status @ 200`, true},
+	}
+	for _, tt := range tests {
+		_, err := parseCondition(tt.text)
+		if (err != nil) != tt.err {
+			t.Errorf("parseCondition(%q) error = %v, want error %v", tt.text, err, tt.err)
+		}
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	resp := &response{
+		Response: &http.Response{
+			StatusCode: 202,
+			Header:     http.Header{"Retry-After": {"5"}, "X-State": {"queued"}},
+		},
+		body: []byte(`{"state":"pending","progress":0.5,"items":[{"id":7}],"done":false}`),
+	}
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{`status == 202`, true},
+		{`status != 202`, false},
+		{`status >= 200 && status < 300`, true},
+		{`body.state == "pending"`, true},
+		{`body.state == 'done'`, false},
+		{`body.progress < 1`, true},
+		{`body.items.0.id == 7`, true},
+		{`body.missing`, false},
+		{`body.missing == null`, true},
+		{`body.done`, false},
+		{`!body.done`, true},
+		{`header.Retry-After > 3`, true},
+		{`header.X-State =~ "^que"`, true},
+		{`header.Missing`, false},
+		{`status == 500 || (body.state == "pending" && header.Retry-After)`, true},
+	}
+	for _, tt := range tests {
+		cond, err := parseCondition(tt.text)
+		if err != nil {
+			t.Fatalf("parseCondition(%q): %v", tt.text, err)
+		}
+		if got := cond.match(resp); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	plain := &response{Response: &http.Response{StatusCode: 200}, body: []byte("ok")}
+	cond, _ := parseCondition(`body == "ok" && body.state == null`)
+	if !cond.match(plain) {
+		t.Errorf("a body that is not JSON should compare as text")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// setupConsole is a no-op outside Windows; terminals there handle UTF-8
+// and ANSI colors natively.
+func setupConsole(f *os.File) (io.Writer, func()) {
+	return f, func() {}
+}
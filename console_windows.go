@@ -0,0 +1,52 @@
+//go:build windows
+
+package main
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalProcessing = 0x0004
+	cpUTF8                          = 65001
+)
+
+var (
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode     = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode     = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleOutputCP = kernel32.NewProc("GetConsoleOutputCP")
+	procSetConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
+)
+
+// setupConsole prepares a Windows console for the pretty output: UTF-8 text
+// and ANSI color sequences. Consoles that cannot interpret ANSI sequences
+// (before Windows 10) get them stripped instead of printed as garbage. The
+// returned function restores the original console settings.
+func setupConsole(f *os.File) (io.Writer, func()) {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if r, _, _ := procGetConsoleMode.Call(uintptr(handle), uintptr(unsafe.Pointer(&mode))); r == 0 {
+		// not a console: redirected to a file or pipe
+		return f, func() {}
+	}
+
+	oldCP, _, _ := procGetConsoleOutputCP.Call()
+	procSetConsoleOutputCP.Call(cpUTF8)
+	restore := func() {
+		procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+		procSetConsoleOutputCP.Call(oldCP)
+	}
+
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return f, restore
+	}
+	if r, _, _ := procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing)); r == 0 {
+		return &ansiStripper{w: f}, restore
+	}
+	return f, restore
+}
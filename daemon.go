@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// daemonRequest is what "rest-blazar send" writes to the daemon: the request
+// flags and the directory relative file names are resolved against, or a
+// request to shut down.
+type daemonRequest struct {
+	Args []string `json:"args,omitempty"`
+	Dir  string   `json:"dir,omitempty"`
+	Stop bool     `json:"stop,omitempty"`
+}
+
+// daemonFrame is one message back to the client: a chunk of output, or the
+// exit code once the request is done.
+type daemonFrame struct {
+	Out  []byte `json:"out,omitempty"`
+	Exit *int   `json:"exit,omitempty"`
+}
+
+// frameWriter sends everything written to it to the client as output frames.
+type frameWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (f *frameWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.enc.Encode(daemonFrame{Out: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (f *frameWriter) exit(code int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enc.Encode(daemonFrame{Exit: &code})
+}
+
+// defaultSocketPath is where the daemon listens unless -socket or
+// REST_BLAZAR_SOCKET says otherwise.
+func defaultSocketPath() string {
+	if path := os.Getenv("REST_BLAZAR_SOCKET"); path != "" {
+		return path
+	}
+	return filepath.Join(privateSocketDir(), "daemon.sock")
+}
+
+// privateSocketDir is the per-user directory for the default socket, in the
+// runtime directory when there is one. It is created with mode 0700.
+func privateSocketDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "rest-blazar")
+	}
+	name := "rest-blazar"
+	if uid := os.Getuid(); uid >= 0 {
+		name = fmt.Sprintf("rest-blazar-%d", uid)
+	}
+	return filepath.Join(os.TempDir(), name)
+}
+
+// daemonOptions holds the flags of the daemon command.
+type daemonOptions struct {
+	socket string
+	idle   int
+	stop   bool
+}
+
+func newDaemonFlags() (*flag.FlagSet, *daemonOptions) {
+	opts := &daemonOptions{}
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	fs.StringVar(&opts.socket, "socket", defaultSocketPath(), "Unix socket to listen on (also read from REST_BLAZAR_SOCKET)")
+	fs.IntVar(&opts.idle, "idle", 30, "Exit after this many minutes without requests (0 never exits)")
+	fs.BoolVar(&opts.stop, "stop", false, "Stop the running daemon")
+	return fs, opts
+}
+
+// runDaemon implements "rest-blazar daemon [flags]". It serves requests from
+// "rest-blazar send" over a unix socket with one shared transport, so
+// connections and TLS sessions stay open between invocations.
+func runDaemon(ctx context.Context, args []string) {
+	fs, opts := newDaemonFlags()
+	fs.Usage = func() { printCommandHelp(fs.Output(), daemonCommand) }
+	fs.Parse(args)
+
+	if opts.stop {
+		if err := checkOwned(opts.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Error: refusing to use daemon socket: %v\n", err)
+			os.Exit(1)
+		}
+		conn, err := net.Dial("unix", opts.socket)
+		if err != nil {
+			fmt.Printf("Error: no daemon listening on %s\n", opts.socket)
+			os.Exit(1)
+		}
+		defer conn.Close()
+		json.NewEncoder(conn).Encode(daemonRequest{Stop: true})
+		var frame daemonFrame
+		json.NewDecoder(conn).Decode(&frame)
+		fmt.Println("Daemon stopped.")
+		return
+	}
+
+	// keep the default socket in a directory only we can enter
+	if dir := filepath.Dir(opts.socket); dir == privateSocketDir() {
+		err := os.MkdirAll(dir, 0700)
+		if err == nil {
+			err = checkOwned(dir)
+		}
+		if err != nil {
+			fmt.Printf("Error: socket directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// a socket file left behind by a daemon that crashed is replaced
+	if conn, err := net.Dial("unix", opts.socket); err == nil {
+		conn.Close()
+		fmt.Printf("Error: a daemon is already listening on %s\n", opts.socket)
+		os.Exit(1)
+	}
+	os.Remove(opts.socket)
+
+	// only the owner may send requests through the daemon
+	ln, err := listenPrivate(opts.socket)
+	if err != nil {
+		fmt.Printf("Error listening on %s: %v\n", opts.socket, err)
+		os.Exit(1)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 5 * time.Minute
+	transport.TLSClientConfig = &tls.Config{ClientSessionCache: tls.NewLRUClientSessionCache(0)}
+
+	d := &daemon{ln: ln, transport: transport, idle: time.Duration(opts.idle) * time.Minute}
+	if d.idle > 0 {
+		d.timer = time.AfterFunc(d.idle, func() { ln.Close() })
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	fmt.Printf("Listening on %s\n", opts.socket)
+	d.serve(ctx)
+	d.wg.Wait()
+	transport.CloseIdleConnections()
+	fmt.Println("Daemon stopped.")
+}
+
+// daemon is the state of a running daemon.
+type daemon struct {
+	ln        net.Listener
+	transport *http.Transport
+	idle      time.Duration
+	timer     *time.Timer
+
+	mu     sync.Mutex
+	active int
+	wg     sync.WaitGroup
+}
+
+func (d *daemon) serve(ctx context.Context) {
+	for {
+		conn, err := d.ln.Accept()
+		if err != nil {
+			return
+		}
+		d.busy(1)
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			defer d.busy(-1)
+			d.handle(ctx, conn)
+		}()
+	}
+}
+
+// busy tracks running requests so the idle timer only runs while there are
+// none.
+func (d *daemon) busy(delta int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.active += delta
+	if d.timer == nil {
+		return
+	}
+	if d.active == 0 {
+		d.timer.Reset(d.idle)
+	} else {
+		d.timer.Stop()
+	}
+}
+
+// handle runs one request from a client and streams its output back.
+func (d *daemon) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	var req daemonRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	out := &frameWriter{enc: json.NewEncoder(conn)}
+	if req.Stop {
+		out.exit(0)
+		d.ln.Close()
+		return
+	}
+
+	// stop the request when the client goes away, e.g. on Ctrl-C
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		conn.Read(make([]byte, 1))
+		cancel()
+	}()
+
+	fs, opts := newRequestFlags()
+	fs.Init("rest-blazar", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() { printCommandHelp(fs.Output(), sendCommand) }
+	if err := fs.Parse(req.Args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			out.exit(0)
+		} else {
+			out.exit(exitUsage)
+		}
+		return
+	}
+
+	// the daemon has no terminal to prompt on or ring the bell of
+	opts.nonInteractive = true
+	opts.bell = false
+	for _, path := range []*string{&opts.bodyFile, &opts.outputFile, &opts.script, &opts.auditLog} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(req.Dir, *path)
+		}
+	}
+
+	out.exit(runCommand(ctx, opts, out, d.transport))
+}
+
+// runSend implements "rest-blazar send [flags]": the request flags are
+// handed to the daemon, which sends the request over its warm connections.
+// Without a daemon the request is sent directly.
+func runSend(ctx context.Context, args []string) {
+	fs, opts := newRequestFlags()
+	fs.Usage = func() { printCommandHelp(fs.Output(), sendCommand) }
+	fs.Parse(args)
+
+	out, restoreConsole := setupConsole(os.Stdout)
+	exit := func(code int) {
+		restoreConsole()
+		os.Exit(code)
+	}
+
+	// never hand credentials to a socket another user could have put there
+	socket := defaultSocketPath()
+	if err := checkOwned(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(out, "Error: refusing to use daemon socket: %v\n", err)
+		exit(exitFailure)
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		exit(runCommand(ctx, opts, out, nil))
+	}
+	defer conn.Close()
+
+	// prompt here, where the terminal is, and pass the answers along, but
+	// not for a request the daemon is going to reject
+	if err := opts.validate(); err != nil {
+		exit(exitCode(out, nil, opts.output, err))
+	}
+	user, pass := opts.username, opts.password
+	if err := resolveCredentials(&opts.username, &opts.password, !opts.nonInteractive); err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		exit(exitUsage)
+	}
+	if opts.username != user || opts.password != pass {
+		args = append(args[:len(args):len(args)], "-user", opts.username, "-pass", opts.password)
+	}
+
+	dir, _ := os.Getwd()
+	if err := json.NewEncoder(conn).Encode(daemonRequest{Args: args, Dir: dir}); err != nil {
+		fmt.Fprintf(out, "Error: sending to daemon: %v\n", err)
+		exit(exitFailure)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	dec := json.NewDecoder(conn)
+	for {
+		var frame daemonFrame
+		if err := dec.Decode(&frame); err != nil {
+			if ctx.Err() != nil {
+				exit(exitCanceled)
+			}
+			fmt.Fprintf(out, "Error: daemon closed the connection: %v\n", err)
+			exit(exitFailure)
+		}
+		out.Write(frame.Out)
+		if frame.Exit != nil {
+			if opts.bell {
+				fmt.Fprint(os.Stderr, "\a")
+			}
+			exit(*frame.Exit)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// Exit codes, one per error category, so wrapping scripts can tell
+// failures apart without parsing messages.
+const (
+	exitFailure    = 1   // anything not covered below, or failing script steps
+	exitUsage      = 2   // invalid flags or missing input (also used by the flag package)
+	exitDNS        = 3   // host name could not be resolved
+	exitConnection = 4   // connection refused, reset or unreachable
+	exitTimeout    = 5   // request or connection timed out, or -retry-if never stopped matching
+	exitTLS        = 6   // certificate or handshake failure
+	exitFile       = 7   // reading or writing a local file failed
+	exitAssertion  = 8   // the response did not match an -expect-header rule or a snapshot
+	exitCanceled   = 130 // interrupted with Ctrl-C
+)
+
+// errorCategory classifies err for the JSON error output and picks the
+// process exit code.
+func errorCategory(err error) (string, int) {
+	var (
+		dnsErr       *net.DNSError
+		opErr        *net.OpError
+		netErr       net.Error
+		pathErr      *os.PathError
+		flagErr      *flagError
+		validErr     validationError
+		unknownCA    x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		certInvalid  x509.CertificateInvalidError
+		certVerify   *tls.CertificateVerificationError
+		recordHeader tls.RecordHeaderError
+	)
+	switch {
+	case errors.Is(err, errScriptFailed):
+		return "failed-requests", exitFailure
+	case errors.Is(err, errAssertionFailed), errors.Is(err, errSnapshotMismatch):
+		return "assertion", exitAssertion
+	case errors.As(err, &validErr), errors.As(err, &flagErr), errors.Is(err, errNotInteractive):
+		return "usage", exitUsage
+	case errors.Is(err, context.Canceled):
+		return "canceled", exitCanceled
+	case errors.As(err, &dnsErr):
+		return "dns", exitDNS
+	case errors.As(err, &unknownCA), errors.As(err, &hostnameErr), errors.As(err, &certInvalid),
+		errors.As(err, &certVerify), errors.As(err, &recordHeader):
+		return "tls", exitTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, errRetryCondition),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout", exitTimeout
+	case errors.As(err, &opErr):
+		return "connection", exitConnection
+	case errors.As(err, &pathErr):
+		return "file", exitFile
+	}
+	return "error", exitFailure
+}
+
+// printJSONError writes err as {"error": {...}} for -output json.
+func printJSONError(w io.Writer, err error) {
+	category, code := errorCategory(err)
+	result := map[string]interface{}{
+		"error": map[string]interface{}{
+			"category": category,
+			"message":  err.Error(),
+			"exitCode": code,
+		},
+	}
+	jsonData, jerr := json.MarshalIndent(result, "", "  ")
+	if jerr != nil {
+		fmt.Fprintf(w, "Error marshaling JSON response: %v\n", jerr)
+		return
+	}
+	fmt.Fprintln(w, string(jsonData))
+}
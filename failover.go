@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// targetResult records how one -failover target fared.
+type targetResult struct {
+	Target     string `json:"target"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// parseFailover splits the -failover value into base URLs.
+func parseFailover(value string) ([]*url.URL, error) {
+	var bases []*url.URL
+	for _, raw := range strings.Split(value, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("%q is not an http or https base URL", raw)
+		}
+		bases = append(bases, u)
+	}
+	return bases, nil
+}
+
+// failoverTargets lists the URLs to try in order: u itself when it is
+// absolute, then u's path and query on every base. A base path is kept as
+// a prefix, so https://b/v2 with /users gives https://b/v2/users.
+func failoverTargets(u *url.URL, bases []*url.URL) []*url.URL {
+	var targets []*url.URL
+	if u.IsAbs() {
+		targets = append(targets, u)
+	}
+	for _, base := range bases {
+		t := *u
+		t.Scheme, t.Host, t.User = base.Scheme, base.Host, base.User
+		t.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+		t.RawPath = ""
+		targets = append(targets, &t)
+	}
+	return targets
+}
+
+// sendFailover sends req to each target in turn until one answers without a
+// network error or a 5xx status. The last target's response is returned
+// even when it is a 5xx, along with how every target fared.
+func sendFailover(ctx context.Context, sess *session, req *http.Request, bases []*url.URL) (*response, error) {
+	targets := failoverTargets(req.URL, bases)
+	var results []targetResult
+	var lastErr error
+	for i, target := range targets {
+		attemptReq := req.Clone(ctx)
+		attemptReq.URL = target
+		attemptReq.Host = ""
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewinding request body: %w", err)
+			}
+			attemptReq.Body = body
+		}
+
+		result := targetResult{Target: redactURL(target)}
+		resp, err := sess.send(ctx, attemptReq)
+		last := i == len(targets)-1
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+			lastErr = err
+		case resp.StatusCode >= 500 && !last:
+			result.StatusCode = resp.StatusCode
+		default:
+			result.StatusCode = resp.StatusCode
+			resp.servedBy = result.Target
+			resp.targets = append(results, result)
+			return resp, nil
+		}
+		results = append(results, result)
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !last && (sess.opts.output == "pretty" || sess.opts.verbose) {
+			outcome := result.Error
+			if outcome == "" {
+				outcome = fmt.Sprint(result.StatusCode)
+			}
+			fmt.Fprintln(sess.w, sess.loc.T("Target %s failed (%s), trying %s...", result.Target, outcome, redactURL(targets[i+1])))
+		}
+	}
+	return nil, fmt.Errorf("all %d targets failed: %w", len(targets), lastErr)
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// command describes one mode of the binary for --help and the man page.
+type command struct {
+	name     string // empty for the default request command
+	summary  string
+	usage    []string
+	desc     string
+	flags    func() *flag.FlagSet
+	groups   []flagGroup
+	examples []example
+}
+
+// flagGroup lists related flags under a common heading.
+type flagGroup struct {
+	title string
+	flags []string
+}
+
+type example struct {
+	desc string
+	cmd  string
+}
+
+// helpTopic is extra documentation reachable with "rest-blazar help <topic>".
+type helpTopic struct {
+	name     string
+	summary  string
+	text     string
+	flags    []string
+	examples []example
+}
+
+var requestCommand = &command{
+	summary: "Send an HTTP request and print the response",
+	usage: []string{
+		"rest-blazar [flags] -url URL",
+		"rest-blazar [flags] -script FILE",
+		"rest-blazar <command> [flags] [args]",
+	},
+	desc: "Sends a single request built from the flags below and prints the response " +
+		"in the selected output format. With -script, every line of the file is sent " +
+		"in turn over one session and a summary is printed at the end. With " +
+		"-output json a script prints one JSON document: the summary, with each " +
+		"step's response under steps[].response.",
+	flags: func() *flag.FlagSet {
+		fs, _ := newRequestFlags()
+		return fs
+	},
+	groups: []flagGroup{
+		{"Request", []string{"url", "method", "headers", "body", "body-file", "json", "form", "vars", "script"}},
+		{"Authentication", []string{"user", "pass", "non-interactive"}},
+		{"Output", []string{"output", "save", "copy", "copy-field", "verbose", "follow-rel", "notify", "bell", "audit-log", "locale"}},
+		{"Connection", []string{"timeout", "http2", "no-redirect", "failover"}},
+		{"Retry", []string{"retries", "retry-delay", "retry-if", "retry-for", "no-throttle"}},
+		{"Polling", []string{"poll-location", "poll-url-field", "poll-until", "poll-interval", "poll-backoff", "poll-timeout"}},
+		{"Assertion", []string{"expect-header"}},
+	},
+	examples: []example{
+		{"Fetch a resource", "rest-blazar -url https://api.example.com/users/1"},
+		{"Create a resource from key=value pairs", "rest-blazar -method POST -url https://api.example.com/users -json name=John,age=30"},
+		{"Send a header and print only the body", "rest-blazar -url https://api.example.com/me -headers 'Authorization: Bearer TOKEN' -output body-only"},
+		{"Fill in a URL variable", "rest-blazar -url 'https://api.example.com/users/{{id}}' -vars id=42"},
+		{"Fetch the next page of a paginated API", "rest-blazar -url https://api.example.com/items -follow-rel next"},
+		{"Copy an access token to the clipboard", "rest-blazar -method POST -url https://auth.example.com/token -form grant_type=client_credentials -copy-field access_token"},
+	},
+}
+
+var robotsCommand = &command{
+	name:    "robots",
+	summary: "Fetch and parse robots.txt for a host",
+	usage:   []string{"rest-blazar robots [flags] host"},
+	desc: "Downloads /robots.txt from the host and prints its user-agent groups, " +
+		"rules and sitemap references.",
+	flags: func() *flag.FlagSet {
+		fs, _ := newRobotsFlags()
+		return fs
+	},
+	examples: []example{
+		{"Show the rules that apply to Googlebot", "rest-blazar robots -agent Googlebot example.com"},
+	},
+}
+
+var sitemapCommand = &command{
+	name:    "sitemap",
+	summary: "Fetch and parse a sitemap",
+	usage:   []string{"rest-blazar sitemap [flags] host-or-url"},
+	desc: "Downloads /sitemap.xml from the host, or the given sitemap URL, and " +
+		"lists the URLs or child sitemaps it contains. Gzipped sitemaps are supported.",
+	flags: func() *flag.FlagSet {
+		fs, _ := newSitemapFlags()
+		return fs
+	},
+	examples: []example{
+		{"List every URL of a sitemap index", "rest-blazar sitemap -expand example.com"},
+		{"Read a sitemap at a custom location", "rest-blazar sitemap -output json https://example.com/sitemaps/blog.xml.gz"},
+	},
+}
+
+var updateCommand = &command{
+	name:    "update",
+	summary: "Replace this binary with the latest release",
+	usage:   []string{"rest-blazar update [flags]"},
+	desc: "Looks up the latest GitHub release, downloads the binary for this " +
+		"platform, verifies it against the release checksums and their signature " +
+		"and replaces the running executable. A build without a signing key " +
+		"refuses to install unless -insecure-skip-signature is given. A release " +
+		"provides rest-blazar_<os>_<arch> binaries (.exe on Windows), " +
+		"checksums.txt in sha256sum format and its signature checksums.txt.sig.",
+	flags: func() *flag.FlagSet {
+		fs, _ := newUpdateFlags()
+		return fs
+	},
+	examples: []example{
+		{"See whether a newer release exists", "rest-blazar update -check"},
+	},
+}
+
+var helpCommand = &command{
+	name:    "help",
+	summary: "Show help for a command or topic",
+	usage:   []string{"rest-blazar help [command | topic]"},
+}
+
+var daemonCommand = &command{
+	name:    "daemon",
+	summary: "Keep connections warm for \"rest-blazar send\"",
+	usage:   []string{"rest-blazar daemon [flags]", "rest-blazar daemon -stop"},
+	desc: "Runs in the foreground and serves requests made with \"rest-blazar send\" " +
+		"over a unix socket that only the current user can use, by default in a " +
+		"private directory under $XDG_RUNTIME_DIR or the temp directory. " +
+		"Connections and TLS sessions are kept open between " +
+		"requests, so scripts that call rest-blazar many times in a row skip the " +
+		"connection setup. Relative file names are resolved in the directory send " +
+		"was run from, and the daemon never prompts; send asks for missing " +
+		"credentials itself. The daemon exits after -idle minutes without requests.",
+	flags: func() *flag.FlagSet {
+		fs, _ := newDaemonFlags()
+		return fs
+	},
+	examples: []example{
+		{"Start a daemon in the background", "rest-blazar daemon &"},
+		{"Stop it again", "rest-blazar daemon -stop"},
+	},
+}
+
+var sendCommand = &command{
+	name:    "send",
+	summary: "Send a request through the running daemon",
+	usage:   []string{"rest-blazar send [flags] -url URL", "rest-blazar send [flags] -script FILE"},
+	desc: "Takes the same flags as a plain request and hands them to the daemon " +
+		"listening on REST_BLAZAR_SOCKET (or the default socket), printing its " +
+		"output and exiting with its exit code. When no daemon is running, the " +
+		"request is sent directly. A socket owned by another user, or open to " +
+		"other users, is refused rather than trusted with the request.",
+	flags: func() *flag.FlagSet {
+		fs, _ := newRequestFlags()
+		return fs
+	},
+	groups: requestCommand.groups,
+	examples: []example{
+		{"Fetch many resources over one connection", "for id in $(seq 100); do rest-blazar send -url https://api.example.com/users/$id -output body-only; done"},
+	},
+}
+
+var snapshotCommand = &command{
+	name:    "snapshot",
+	summary: "Save a response and check later that it has not changed",
+	usage: []string{
+		"rest-blazar snapshot save NAME [flags] -url URL",
+		"rest-blazar snapshot check NAME [flags]",
+	},
+	desc: "save sends the request and stores its status, Content-Type and any " +
+		"-keep-header headers, and its JSON body with keys sorted and -ignore paths " +
+		"removed, as NAME.json in -snapshot-dir. check sends the request again, to " +
+		"the saved method and URL unless -url is given, and lists every " +
+		"difference; it exits with code 8 when there are any. Ignore rules and " +
+		"headers saved with the snapshot are used by check too. Secrets in the URL " +
+		"are redacted before saving, so such a URL must be given to check again " +
+		"with -url. All request flags are accepted.",
+	flags: func() *flag.FlagSet {
+		fs, _, _ := newSnapshotFlags()
+		return fs
+	},
+	groups: append(requestCommand.groups[:len(requestCommand.groups):len(requestCommand.groups)],
+		flagGroup{"Snapshot", []string{"snapshot-dir", "keep-header", "ignore"}}),
+	examples: []example{
+		{"Record the current user list, ignoring timestamps", "rest-blazar snapshot save users -url https://api.example.com/users -ignore '*.updatedAt' -keep-header Cache-Control"},
+		{"Verify it in CI", "rest-blazar snapshot check users -non-interactive"},
+	},
+}
+
+var manCommand = &command{
+	name:    "man",
+	summary: "Print the man page in roff format",
+	usage:   []string{"rest-blazar man > rest-blazar.1"},
+}
+
+// commands lists the subcommands in the order help shows them.
+var commands = []*command{robotsCommand, sitemapCommand, updateCommand, daemonCommand, sendCommand, snapshotCommand, helpCommand, manCommand}
+
+var helpTopics = []helpTopic{
+	{
+		name:    "auth",
+		summary: "Basic auth, tokens and credential prompts",
+		text: "Basic auth credentials are given with -user and -pass. When only one of " +
+			"them is set, the other is asked for on the terminal, with the password " +
+			"hidden. Other schemes such as bearer tokens are sent as a regular header. " +
+			"Set -non-interactive in CI so missing input fails instead of prompting. " +
+			"The Authorization and Cookie headers are only sent on to a -follow-rel " +
+			"link or -poll-location status URL on the same scheme, host and port.",
+		flags: []string{"user", "pass", "headers", "non-interactive"},
+		examples: []example{
+			{"Prompt for the password", "rest-blazar -url https://api.example.com/me -user alice"},
+			{"Send a bearer token", "rest-blazar -url https://api.example.com/me -headers 'Authorization: Bearer TOKEN'"},
+		},
+	},
+	{
+		name:    "output",
+		summary: "Output formats and saving responses",
+		text: "-output selects how the response is printed: pretty (colored status, " +
+			"headers, parsed Link headers and body), json (a single JSON object), " +
+			"headers-only or body-only. -save writes the raw body to a file in " +
+			"addition to printing it, -copy places it on the clipboard (or just one " +
+			"JSON field with -copy-field), and -verbose also prints the request.",
+		flags: []string{"output", "save", "copy", "copy-field", "verbose"},
+		examples: []example{
+			{"Pipe the body into jq", "rest-blazar -url https://api.example.com/users -output body-only | jq ."},
+			{"Keep a copy of the body", "rest-blazar -url https://example.com/report.csv -save report.csv"},
+		},
+	},
+	{
+		name:    "retries",
+		summary: "Retrying failed requests",
+		text: "A request that fails with a network error, or whose body cannot be " +
+			"read, is sent again up to -retries more times, waiting -retry-delay " +
+			"seconds between attempts. Responses with an error status are not retried " +
+			"unless they match -retry-if. " +
+			"With -verbose the outcome, latency and backoff of every attempt is " +
+			"printed, and -output json adds an attempts array. " +
+			"-retry-if also retries responses that match an expression, which is how " +
+			"to poll APIs that answer 200 while a job is still running. Expressions " +
+			"compare status, body, body.<path> (e.g. body.items.0.id) and " +
+			"header.<Name> with ==, !=, <, <=, >, >= or =~ (regular expression) and " +
+			"combine them with &&, || and !. Polling stops after -retries attempts " +
+			"when set, or -retry-for seconds, and exits with code 5 if the response " +
+			"still matches. -retry-delay must be at least 1 with -retry-if, and a " +
+			"Retry-After header on a matching response, such as a 429, makes the " +
+			"wait longer when it asks for more.",
+		flags: []string{"retries", "retry-delay", "retry-if", "retry-for", "timeout", "verbose"},
+		examples: []example{
+			{"Try up to four times, two seconds apart", "rest-blazar -url https://api.example.com/health -retries 3 -retry-delay 2"},
+			{"Poll a job until it has finished", "rest-blazar -url https://api.example.com/jobs/7 -retry-if 'status==409 || body.state==\"pending\"' -retry-delay 5 -retry-for 300"},
+		},
+	},
+	{
+		name:    "failover",
+		summary: "Trying several servers in turn",
+		text: "-failover lists base URLs to fall back to, in order. When the request " +
+			"fails with a network error or timeout, or gets a 5xx status, it is sent " +
+			"again with the same path and query to the next base; a path in the base " +
+			"is kept as a prefix. The -url is tried first, or, when it is only a " +
+			"path, the first base. Each target gets the full -retries. The output " +
+			"names the target that served the response, and -output json lists how " +
+			"every target fared.",
+		flags: []string{"failover", "url", "retries", "timeout"},
+		examples: []example{
+			{"Fall back to the standby region", "rest-blazar -url https://eu.api.example.com/health -failover https://us.api.example.com"},
+			{"Ask a pool of replicas for the same path", "rest-blazar -url /status -failover https://node1:8443,https://node2:8443,https://node3:8443"},
+		},
+	},
+	{
+		name:    "polling",
+		summary: "Waiting for asynchronous jobs",
+		text: "APIs that start long-running work often answer 202 Accepted with a " +
+			"Location header pointing at a status resource. With -poll-location that " +
+			"resource is fetched until it is no longer 202, or until it matches the " +
+			"-poll-until expression (see \"help retries\" for the syntax), and the " +
+			"final response is printed, saved and checked instead of the 202. A " +
+			"redirect to the finished resource is followed. -poll-url-field reads the " +
+			"status URL from the 202 body instead. Polls start -poll-interval seconds " +
+			"apart, grow by -poll-backoff each time, honor Retry-After and give up " +
+			"with exit code 5 after -poll-timeout seconds.",
+		flags: []string{"poll-location", "poll-url-field", "poll-until", "poll-interval", "poll-backoff", "poll-timeout"},
+		examples: []example{
+			{"Start an export and wait for it", "rest-blazar -method POST -url https://api.example.com/exports -json format=csv -poll-location"},
+			{"Poll a status document until the job settles", "rest-blazar -method POST -url https://api.example.com/jobs -json task=reindex -poll-location -poll-url-field links.status -poll-until 'body.state==\"done\" || body.state==\"failed\"'"},
+		},
+	},
+	{
+		name:    "rate-limits",
+		summary: "Respecting server rate limits",
+		text: "When a response carries RateLimit-*, X-RateLimit-* or a combined " +
+			"RateLimit header, or Retry-After, the next request of the same run " +
+			"(script steps, -follow-rel) waits as the server asks. Retry-After and " +
+			"an exhausted budget pause until the given time or the reset; once less " +
+			"than a tenth of the budget is left, requests are spread out over the " +
+			"rest of the window. -verbose prints the budget after every response. " +
+			"Set -no-throttle to send requests as fast as possible.",
+		flags: []string{"no-throttle", "verbose", "script"},
+		examples: []example{
+			{"Run a batch without getting rate limited", "rest-blazar -script requests.txt -verbose"},
+		},
+	},
+	{
+		name:    "assertions",
+		summary: "Checking response headers",
+		text: "Each -expect-header rule must hold for the response, or the command " +
+			"exits with code 8 after printing it. \"Name\" requires the header and " +
+			"\"!Name\" forbids it. \"Name: value\" matches a value exactly and " +
+			"\"Name: ~regexp\" by regular expression. \"Name: >0\" compares the value " +
+			"as a number with >, >=, <, <=, == or !=; a value that only starts with " +
+			"one of these, such as <https://x/2>, is matched exactly. \"Name: #2\" or " +
+			"\"Name: #>=2\" counts how often the header occurs. In a script every " +
+			"step is checked and a mismatch fails the step.",
+		flags: []string{"expect-header", "output"},
+		examples: []example{
+			{"Make sure the rate limit is not exhausted", "rest-blazar -url https://api.example.com/items -expect-header 'X-RateLimit-Remaining: >0'"},
+			{"Require a JSON response without a session cookie", "rest-blazar -url https://api.example.com/items -expect-header 'Content-Type: ~^application/json' -expect-header '!Set-Cookie'"},
+		},
+	},
+	{
+		name:    "errors",
+		summary: "Error categories and exit codes",
+		text: "Failures exit with a code that names their category: 1 for other " +
+			"errors and failing script steps, 2 for invalid flags or missing input, " +
+			"3 for DNS failures, 4 for refused or reset connections, 5 for timeouts " +
+			"(including -retry-if still matching), " +
+			"6 for TLS and certificate errors, 7 for local file errors, 8 when an " +
+			"-expect-header rule or snapshot does not match and 130 when " +
+			"interrupted. With -output json the error is printed as a JSON object " +
+			"with category, message and exitCode fields instead of plain text. " +
+			"This applies to requests, robots and sitemap; update always prints " +
+			"plain text and exits with 1. Retry, rate limit and polling notices " +
+			"are only printed in pretty output or with -verbose.",
+		flags: []string{"output"},
+		examples: []example{
+			{"Branch on the failure type in a script", "rest-blazar -url https://api.example.com/health -output json || echo \"failed with $?\""},
+		},
+	},
+}
+
+// runHelp implements "rest-blazar help [command | topic]".
+func runHelp(args []string) {
+	if len(args) == 0 {
+		printCommandHelp(os.Stdout, requestCommand)
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			printCommandHelp(os.Stdout, cmd)
+			return
+		}
+	}
+	for _, topic := range helpTopics {
+		if topic.name == args[0] {
+			printTopicHelp(os.Stdout, topic)
+			return
+		}
+	}
+	fmt.Printf("Error: unknown help topic %q\n", args[0])
+	os.Exit(1)
+}
+
+func printCommandHelp(w io.Writer, cmd *command) {
+	for i, u := range cmd.usage {
+		if i == 0 {
+			fmt.Fprintf(w, "Usage: %s\n", u)
+		} else {
+			fmt.Fprintf(w, "       %s\n", u)
+		}
+	}
+	fmt.Fprintf(w, "\n%s.\n", cmd.summary)
+	if cmd.desc != "" {
+		fmt.Fprintf(w, "\n%s\n", wrap(cmd.desc, 76, ""))
+	}
+
+	if cmd == requestCommand {
+		fmt.Fprintln(w, "\nCommands:")
+		for _, sub := range commands {
+			fmt.Fprintf(w, "  %-10s %s\n", sub.name, sub.summary)
+		}
+	}
+
+	if cmd.flags != nil {
+		fs := cmd.flags()
+		for _, group := range commandGroups(cmd, fs) {
+			fmt.Fprintf(w, "\n%s flags:\n", group.title)
+			for _, name := range group.flags {
+				printFlag(w, fs.Lookup(name))
+			}
+		}
+	}
+
+	printExamples(w, cmd.examples)
+
+	if cmd == requestCommand {
+		names := make([]string, len(helpTopics))
+		for i, topic := range helpTopics {
+			names[i] = topic.name
+		}
+		fmt.Fprintf(w, "\nHelp topics: %s (rest-blazar help <topic>)\n", strings.Join(names, ", "))
+	}
+}
+
+func printTopicHelp(w io.Writer, topic helpTopic) {
+	fmt.Fprintf(w, "%s\n\n%s\n", topic.summary, wrap(topic.text, 76, ""))
+	if len(topic.flags) > 0 {
+		fs := requestCommand.flags()
+		fmt.Fprintln(w, "\nFlags:")
+		for _, name := range topic.flags {
+			printFlag(w, fs.Lookup(name))
+		}
+	}
+	printExamples(w, topic.examples)
+}
+
+func printExamples(w io.Writer, examples []example) {
+	if len(examples) == 0 {
+		return
+	}
+	fmt.Fprint(w, "\nExamples:\n")
+	for i, ex := range examples {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "  # %s\n  %s\n", ex.desc, ex.cmd)
+	}
+}
+
+func printFlag(w io.Writer, f *flag.Flag) {
+	name, usage := flag.UnquoteUsage(f)
+	left := "-" + f.Name
+	if name != "" {
+		left += " " + name
+	}
+	if def := flagDefault(f, name); def != "" {
+		usage += " (default " + def + ")"
+	}
+	if len(left) > 20 {
+		fmt.Fprintf(w, "  %s\n  %-20s %s\n", left, "", wrap(usage, 54, strings.Repeat(" ", 23)))
+		return
+	}
+	fmt.Fprintf(w, "  %-20s %s\n", left, wrap(usage, 54, strings.Repeat(" ", 23)))
+}
+
+// commandGroups returns the command's flag groups, with any flag missing
+// from them collected under "Other" so nothing goes undocumented.
+func commandGroups(cmd *command, fs *flag.FlagSet) []flagGroup {
+	groups := cmd.groups
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		for _, name := range group.flags {
+			seen[name] = true
+		}
+	}
+	var other []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if !seen[f.Name] {
+			other = append(other, f.Name)
+		}
+	})
+	if len(other) > 0 {
+		title := "Other"
+		if len(groups) == 0 {
+			title = "Command"
+		}
+		groups = append(groups[:len(groups):len(groups)], flagGroup{title, other})
+	}
+	return groups
+}
+
+// flagDefault returns the default value worth mentioning, or "".
+func flagDefault(f *flag.Flag, typeName string) string {
+	switch f.DefValue {
+	case "", "false", "0":
+		return ""
+	}
+	if typeName == "string" {
+		return fmt.Sprintf("%q", f.DefValue)
+	}
+	return f.DefValue
+}
+
+// wrap breaks text into lines of at most width characters, prefixing
+// every line but the first with indent.
+func wrap(text string, width int, indent string) string {
+	var b strings.Builder
+	lineLen := 0
+	for _, word := range strings.Fields(text) {
+		if lineLen > 0 && lineLen+1+len(word) > width {
+			b.WriteString("\n" + indent)
+			lineLen = 0
+		} else if lineLen > 0 {
+			b.WriteByte(' ')
+			lineLen++
+		}
+		b.WriteString(word)
+		lineLen += len(word)
+	}
+	return b.String()
+}
+
+// printManPage writes a roff man page covering every command and topic.
+func printManPage(w io.Writer) {
+	fmt.Fprintf(w, ".TH REST-BLAZAR 1 %q \"rest-blazar\" \"User Commands\"\n", time.Now().Format("2006-01-02"))
+	fmt.Fprintf(w, ".SH NAME\nrest-blazar \\- %s\n", roff(strings.ToLower(requestCommand.summary[:1])+requestCommand.summary[1:]))
+
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	for _, u := range requestCommand.usage {
+		fmt.Fprintf(w, ".B %s\n.br\n", roff(u))
+	}
+	fmt.Fprintf(w, ".SH DESCRIPTION\n%s\n", roff(requestCommand.desc))
+
+	fs := requestCommand.flags()
+	fmt.Fprintln(w, ".SH OPTIONS")
+	for _, group := range commandGroups(requestCommand, fs) {
+		fmt.Fprintf(w, ".SS %s\n", roff(group.title))
+		for _, name := range group.flags {
+			manFlag(w, fs.Lookup(name))
+		}
+	}
+
+	fmt.Fprintln(w, ".SH COMMANDS")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, ".SS %s\n", roff(cmd.name))
+		for _, u := range cmd.usage {
+			fmt.Fprintf(w, ".B %s\n.br\n", roff(u))
+		}
+		fmt.Fprintf(w, ".PP\n%s.\n", roff(cmd.summary))
+		if cmd.desc != "" {
+			fmt.Fprintf(w, "%s\n", roff(cmd.desc))
+		}
+		if cmd.flags != nil {
+			manCommandFlags(w, cmd)
+		}
+		manExamples(w, cmd.examples)
+	}
+
+	fmt.Fprintln(w, ".SH TOPICS")
+	for _, topic := range helpTopics {
+		fmt.Fprintf(w, ".SS %s\n%s\n", roff(topic.name), roff(topic.text))
+		manExamples(w, topic.examples)
+	}
+
+	fmt.Fprintln(w, ".SH EXAMPLES")
+	manExamples(w, requestCommand.examples)
+}
+
+// manCommandFlags lists the flags of cmd. The request flags that send and
+// snapshot share are already under OPTIONS, so only the others are listed.
+func manCommandFlags(w io.Writer, cmd *command) {
+	request := make(map[string]bool)
+	for _, group := range requestCommand.groups {
+		request[group.title] = true
+	}
+	fs := cmd.flags()
+	shared := false
+	for _, group := range commandGroups(cmd, fs) {
+		if request[group.title] {
+			shared = true
+			continue
+		}
+		for _, name := range group.flags {
+			manFlag(w, fs.Lookup(name))
+		}
+	}
+	if shared {
+		fmt.Fprintln(w, ".PP\nAccepts every request flag listed under OPTIONS.")
+	}
+}
+
+func manFlag(w io.Writer, f *flag.Flag) {
+	name, usage := flag.UnquoteUsage(f)
+	fmt.Fprintf(w, ".TP\n.B \\-%s", roff(f.Name))
+	if name != "" {
+		fmt.Fprintf(w, " \\fI%s\\fR", roff(name))
+	}
+	if def := flagDefault(f, name); def != "" {
+		usage += " (default " + def + ")"
+	}
+	fmt.Fprintf(w, "\n%s\n", roff(usage))
+}
+
+func manExamples(w io.Writer, examples []example) {
+	for _, ex := range examples {
+		fmt.Fprintf(w, ".PP\n%s:\n.PP\n.RS\n.nf\n%s\n.fi\n.RE\n", roff(ex.desc), roff(ex.cmd))
+	}
+}
+
+// roff escapes text for use in a man page.
+func roff(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestManPageListsFlagsOnce(t *testing.T) {
+	var b strings.Builder
+	printManPage(&b)
+	page := b.String()
+	count := func(name string) int {
+		entry := "\n.B " + roff("-"+name)
+		return strings.Count(page, entry+"\n") + strings.Count(page, entry+" ")
+	}
+
+	// robots, sitemap and update have -output and -timeout flags of their own
+	own := map[string]bool{"output": true, "timeout": true}
+	requestCommand.flags().VisitAll(func(f *flag.Flag) {
+		if n := count(f.Name); n != 1 && !own[f.Name] {
+			t.Errorf("-%s is listed %d times, want once", f.Name, n)
+		}
+	})
+	for _, name := range []string{"snapshot-dir", "keep-header", "ignore", "socket", "insecure-skip-signature"} {
+		if count(name) != 1 {
+			t.Errorf("-%s is listed %d times, want once", name, count(name))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// catalogs holds one JSON message catalog per locale, named after its
+// BCP 47 tag (e.g. locales/de-DE.json). A catalog maps the English format
+// strings used in the code to their translation, and sets the separators
+// used for numbers. To add a language, copy an existing file and translate
+// its values; format verbs must stay in the same order.
+//
+//go:embed locales/*.json
+var catalogs embed.FS
+
+type catalog struct {
+	Decimal  string            `json:"decimal"`
+	Group    string            `json:"group"`
+	Messages map[string]string `json:"messages"`
+}
+
+// localizer translates user-facing messages and formats durations and
+// sizes for one locale. A nil localizer prints English with Go's native
+// duration formatting, which is also what the tool prints without -locale.
+type localizer struct {
+	tag string
+	catalog
+}
+
+// newLocalizer loads the catalog for tag, falling back to any catalog of
+// the same language, so "de" and "de-AT" both pick up de-DE. English needs
+// no catalog.
+func newLocalizer(tag string) (*localizer, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	tag = strings.ReplaceAll(tag, "_", "-")
+	lang, _, _ := strings.Cut(tag, "-")
+	candidates := []string{tag, lang}
+	if matches, err := fs.Glob(catalogs, "locales/"+lang+"-*.json"); err == nil {
+		for _, m := range matches {
+			candidates = append(candidates, strings.TrimSuffix(path.Base(m), ".json"))
+		}
+	}
+
+	l := &localizer{tag: tag, catalog: catalog{Decimal: ".", Group: ","}}
+	for _, name := range candidates {
+		data, err := catalogs.ReadFile("locales/" + name + ".json")
+		if err != nil {
+			continue
+		}
+		if err := json.Unmarshal(data, &l.catalog); err != nil {
+			return nil, fmt.Errorf("locale %s: %w", name, err)
+		}
+		return l, nil
+	}
+	if strings.EqualFold(lang, "en") {
+		return l, nil
+	}
+	return nil, fmt.Errorf("unsupported locale %q", tag)
+}
+
+// T formats a message, translating format first when the catalog has it.
+func (l *localizer) T(format string, args ...interface{}) string {
+	if l != nil {
+		if translated, ok := l.Messages[format]; ok && translated != "" {
+			format = translated
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Duration formats d in the largest fitting unit with two decimals.
+func (l *localizer) Duration(d time.Duration) string {
+	if l == nil {
+		return d.String()
+	}
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return l.decimal(d.Seconds(), 2) + " s"
+	case d >= time.Millisecond:
+		return l.decimal(float64(d)/float64(time.Millisecond), 2) + " ms"
+	default:
+		return l.decimal(float64(d)/float64(time.Microsecond), 0) + " µs"
+	}
+}
+
+// Size formats a byte count using binary units.
+func (l *localizer) Size(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return l.Number(n) + " " + units[0]
+	}
+	return l.decimal(value, 1) + " " + units[unit]
+}
+
+// Number formats an integer with the locale's digit grouping.
+func (l *localizer) Number(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	if l == nil {
+		return s
+	}
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String()
+}
+
+func (l *localizer) decimal(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	if l == nil {
+		return s
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	n, _ := strconv.ParseInt(whole, 10, 64)
+	if frac == "" {
+		return l.Number(n)
+	}
+	return l.Number(n) + l.Decimal + frac
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lookupJSON decodes data and walks a dot separated path such as
+// "data.items.0.id". Numeric segments index into arrays. An empty path
+// returns the whole document.
+func lookupJSON(data []byte, path string) (interface{}, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("body is not JSON: %v", err)
+	}
+	return walkJSON(doc, path)
+}
+
+// walkJSON follows path through an already decoded document.
+func walkJSON(doc interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return doc, nil
+	}
+
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("no field %q in %s", key, path)
+			}
+			cur = v
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("invalid index %q in %s", key, path)
+			}
+			cur = node[i]
+		default:
+			return nil, fmt.Errorf("cannot look up %q in a scalar value in %s", key, path)
+		}
+	}
+	return cur, nil
+}
+
+// jsonText renders a value found by lookupJSON; strings are returned
+// without quotes so they can be used as is.
+func jsonText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(out)
+}
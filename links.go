@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// link is a single link-value from an RFC 8288 Link header.
+type link struct {
+	URL    string            `json:"url"`
+	Rel    []string          `json:"rel,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// String renders the link back in header form, e.g. <url>; rel="next".
+func (l link) String() string {
+	s := "<" + l.URL + ">"
+	if len(l.Rel) > 0 {
+		s += fmt.Sprintf("; rel=%q", strings.Join(l.Rel, " "))
+	}
+	keys := make([]string, 0, len(l.Params))
+	for k := range l.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s += fmt.Sprintf("; %s=%q", k, l.Params[k])
+	}
+	return s
+}
+
+// parseLinkHeader parses every Link header value into its link-values.
+// Malformed entries are skipped rather than failing the whole header.
+func parseLinkHeader(values []string) []link {
+	var links []link
+	for _, v := range values {
+		links = append(links, parseLinkValue(v)...)
+	}
+	return links
+}
+
+func parseLinkValue(s string) []link {
+	var links []link
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" || s[0] != '<' {
+			return links
+		}
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			return links
+		}
+		l := link{URL: strings.TrimSpace(s[1:end])}
+		s = s[end+1:]
+
+		// link-params: *( OWS ";" OWS link-param )
+		for {
+			s = strings.TrimLeft(s, " \t")
+			if s == "" || s[0] != ';' {
+				break
+			}
+			s = strings.TrimLeft(s[1:], " \t")
+
+			i := strings.IndexAny(s, "=;, \t")
+			if i < 0 {
+				i = len(s)
+			}
+			name := strings.ToLower(s[:i])
+			s = strings.TrimLeft(s[i:], " \t")
+
+			var value string
+			if s != "" && s[0] == '=' {
+				value, s = parseParamValue(strings.TrimLeft(s[1:], " \t"))
+			}
+			if name == "" {
+				continue
+			}
+			if name == "rel" {
+				// the first occurrence wins, per RFC 8288 section 3.3
+				if l.Rel == nil {
+					l.Rel = strings.Fields(strings.ToLower(value))
+				}
+				continue
+			}
+			if l.Params == nil {
+				l.Params = make(map[string]string)
+			}
+			if _, ok := l.Params[name]; !ok {
+				l.Params[name] = value
+			}
+		}
+		links = append(links, l)
+	}
+}
+
+// parseParamValue reads a token or quoted-string and returns it with the
+// remaining input.
+func parseParamValue(s string) (string, string) {
+	if s == "" || s[0] != '"' {
+		i := strings.IndexAny(s, ";, \t")
+		if i < 0 {
+			return s, ""
+		}
+		return s[:i], s[i:]
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// findLink returns the first link carrying the given relation type.
+func findLink(links []link, rel string) (link, bool) {
+	rel = strings.ToLower(rel)
+	for _, l := range links {
+		for _, r := range l.Rel {
+			if r == rel {
+				return l, true
+			}
+		}
+	}
+	return link{}, false
+}
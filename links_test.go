@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []link
+	}{
+		{
+			name:   "empty",
+			values: nil,
+			want:   nil,
+		},
+		{
+			name:   "single",
+			values: []string{`<https://api.example.com/items?page=2>; rel="next"`},
+			want:   []link{{URL: "https://api.example.com/items?page=2", Rel: []string{"next"}}},
+		},
+		{
+			name:   "several in one value",
+			values: []string{`<https://x/2>; rel="next", <https://x/9>; rel="last"`},
+			want: []link{
+				{URL: "https://x/2", Rel: []string{"next"}},
+				{URL: "https://x/9", Rel: []string{"last"}},
+			},
+		},
+		{
+			name:   "several header lines",
+			values: []string{`<https://x/1>; rel=prev`, `<https://x/3>; rel=next`},
+			want: []link{
+				{URL: "https://x/1", Rel: []string{"prev"}},
+				{URL: "https://x/3", Rel: []string{"next"}},
+			},
+		},
+		{
+			name:   "multiple relation types, lowercased",
+			values: []string{`<https://x/>; rel="Start Index"`},
+			want:   []link{{URL: "https://x/", Rel: []string{"start", "index"}}},
+		},
+		{
+			name:   "first rel wins",
+			values: []string{`<https://x/>; rel=next; rel=prev`},
+			want:   []link{{URL: "https://x/", Rel: []string{"next"}}},
+		},
+		{
+			name:   "params with quoting and commas",
+			values: []string{`<https://x/>; rel=alternate; title="a, \"b\""; type=text/html; hreflang=de`},
+			want: []link{{
+				URL:    "https://x/",
+				Rel:    []string{"alternate"},
+				Params: map[string]string{"title": `a, "b"`, "type": "text/html", "hreflang": "de"},
+			}},
+		},
+		{
+			name:   "whitespace around parts",
+			values: []string{` < https://x/2 > ;  rel = "next" ,<https://x/3>;rel=last`},
+			want: []link{
+				{URL: "https://x/2", Rel: []string{"next"}},
+				{URL: "https://x/3", Rel: []string{"last"}},
+			},
+		},
+		{
+			name:   "param without value",
+			values: []string{`<https://x/>; rel=next; crossorigin`},
+			want:   []link{{URL: "https://x/", Rel: []string{"next"}, Params: map[string]string{"crossorigin": ""}}},
+		},
+		{
+			name:   "malformed value is skipped",
+			values: []string{`https://x/2; rel=next`, `<https://x/3; rel=next`, `<https://x/4>; rel=next`},
+			want:   []link{{URL: "https://x/4", Rel: []string{"next"}}},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseLinkHeader(tt.values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
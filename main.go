@@ -1,138 +1,226 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 )
 
 func main() {
-	// command-line flags for customization
-	method := flag.String("method", "GET", "HTTP method to use")
-	url := flag.String("url", "", "URL to send request to")
-	body := flag.String("body", "", "Body to send with request")
-	headers := flag.String("headers", "", "Headers to send with request")
-	timeout := flag.Int("timeout", 10, "Timeout in seconds")
-	output := flag.String("output", "pretty", "Output format: pretty, json, headers-only, body-only")
-	outputFile := flag.String("save", "", "Save response body to file")
-	bodyFile := flag.String("body-file", "", "File containing the request body")
-	username := flag.String("user", "", "Username for basic auth")
-	password := flag.String("pass", "", "Password for basic auth")
-	verbose := flag.Bool("verbose", false, "Show request details")
-	noRedirect := flag.Bool("no-redirect", false, "Don't follow redirects")
-	http2 := flag.Bool("http2", false, "Force HTTP/2 protocol")
-	jsonData := flag.String("json", "", "JSON data as key=value pairs (e.g. name=John,age=30)")
-	formData := flag.String("form", "", "Form data as key=value pairs (e.g. name=John,age=30)")
-	retries := flag.Int("retries", 0, "Number of retry attempts for failed requests")
-	retryDelay := flag.Int("retry-delay", 1, "Delay between retries in seconds")
-	flag.Parse()
-
-	// check for url
-	if *url == "" {
-		fmt.Println("Error: URL is required.")
-		os.Exit(1)
-	}
-
-	// create http client with custom settings
-	client := http.Client{
-		Timeout: time.Duration(*timeout) * time.Second,
-	}
-
-	// Configure HTTP/2 transport if requested
-	if *http2 {
-		transport := &http.Transport{
-			ForceAttemptHTTP2: true,
+	// cancel in-flight requests and retry waits on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// convenience modes are selected by the first argument
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "robots":
+			runRobots(ctx, os.Args[2:])
+			return
+		case "sitemap":
+			runSitemap(ctx, os.Args[2:])
+			return
+		case "update":
+			runUpdate(ctx, os.Args[2:])
+			return
+		case "help":
+			runHelp(os.Args[2:])
+			return
+		case "man":
+			printManPage(os.Stdout)
+			return
+		case "daemon":
+			runDaemon(ctx, os.Args[2:])
+			return
+		case "send":
+			runSend(ctx, os.Args[2:])
+			return
+		case "snapshot":
+			runSnapshot(ctx, os.Args[2:])
+			return
 		}
-		client.Transport = transport
 	}
 
-	// configure redirect policy
-	if *noRedirect {
-		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		}
+	// command-line flags for customization
+	fs, opts := newRequestFlags()
+	fs.Usage = func() { printCommandHelp(fs.Output(), requestCommand) }
+	fs.Parse(os.Args[1:])
+
+	// make colors and UTF-8 work on Windows consoles
+	out, restoreConsole := setupConsole(os.Stdout)
+	code := runCommand(ctx, opts, out, nil)
+	restoreConsole()
+	stop()
+	os.Exit(code)
+}
+
+// runCommand runs the request command described by opts, reports any error
+// to w and returns the exit code. A nil transport gives every run its own
+// connections; the daemon passes one shared transport to keep them warm.
+func runCommand(ctx context.Context, opts *options, w io.Writer, transport http.RoundTripper) int {
+	loc, err := newLocalizer(opts.locale)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return exitUsage
 	}
 
-	// determine the request body
-	var reqBody io.Reader
-	if *bodyFile != "" {
-		fileData, err := os.ReadFile(*bodyFile)
-		if err != nil {
-			fmt.Printf("Error reading body file: %v\n", err)
-			os.Exit(1)
+	return exitCode(w, loc, opts.output, run(ctx, opts, loc, w, transport))
+}
+
+// exitCode reports err, if any, to w in the given output format and returns
+// the exit code for it.
+func exitCode(w io.Writer, loc *localizer, output string, err error) int {
+	if err == nil {
+		return 0
+	}
+	// a failed script has already reported its steps in the summary
+	if !errors.Is(err, errScriptFailed) {
+		if output == "json" {
+			printJSONError(w, err)
+		} else {
+			fmt.Fprintln(w, loc.T("Error: %v", err))
 		}
-		reqBody = strings.NewReader(string(fileData))
-	} else if *jsonData != "" {
-		// Process JSON data from command line
-		jsonMap := make(map[string]interface{})
-		pairs := strings.Split(*jsonData, ",")
-		for _, pair := range pairs {
-			parts := strings.SplitN(pair, "=", 2)
-			if len(parts) == 2 {
-				jsonMap[parts[0]] = parts[1]
+	}
+	_, code := errorCategory(err)
+	return code
+}
+
+// run executes the default request command: either a single request or a
+// script, writing all output to w in the language of loc.
+func run(ctx context.Context, opts *options, loc *localizer, w io.Writer, transport http.RoundTripper) error {
+	// reject contradictory or malformed flags before doing any work
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	// collect {{variable}} values and fill in missing credentials
+	if err := resolveCredentials(&opts.username, &opts.password, !opts.nonInteractive); err != nil {
+		return err
+	}
+
+	sess, err := newSession(opts, loc, w, transport)
+	if err != nil {
+		return err
+	}
+	defer sess.close()
+
+	start := time.Now()
+	if opts.script != "" {
+		// run a batch script with a shared session instead of a single request
+		err = runScript(ctx, sess, opts.script)
+		if opts.notify || opts.bell {
+			outcome := "All requests succeeded"
+			if err != nil {
+				outcome = "Some requests failed"
 			}
+			notifyCompletion(opts.notify, opts.bell, opts.script, outcome, time.Since(start))
 		}
-		jsonBytes, err := json.Marshal(jsonMap)
+		return err
+	}
+
+	subject := opts.method + " " + opts.url
+	resp, err := runRequest(ctx, sess)
+	if opts.notify || opts.bell {
 		if err != nil {
-			fmt.Printf("Error creating JSON: %v\n", err)
-			os.Exit(1)
+			notifyCompletion(opts.notify, opts.bell, subject, "Failed", time.Since(start))
+		} else {
+			notifyCompletion(opts.notify, opts.bell, subject, resp.Status, time.Since(start))
 		}
-		reqBody = strings.NewReader(string(jsonBytes))
-		// Set JSON content type if not overridden
-		if req.Header.Get("Content-Type") == "" {
-			req.Header.Set("Content-Type", "application/json")
+	}
+	return err
+}
+
+// runRequest sends the request described by the flags, handles -save,
+// -copy and -follow-rel, and prints the response.
+func runRequest(ctx context.Context, sess *session) (*http.Response, error) {
+	opts := sess.opts
+	w := sess.w
+	loc := sess.loc
+
+	_, resp, err := exchange(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+	data := resp.body
+
+	// Display timing stats in verbose mode
+	if opts.verbose {
+		fmt.Fprintf(w, "\n%s\n", loc.T("Request completed in %s", loc.Duration(resp.duration)))
+	}
+
+	if opts.outputFile != "" {
+		err := os.WriteFile(opts.outputFile, data, 0644)
+		if err != nil {
+			fmt.Fprintln(w, loc.T("Error saving response to file: %v", err))
+		} else {
+			fmt.Fprintln(w, loc.T("Response saved to %s", opts.outputFile))
 		}
-	} else if *formData != "" {
-		// Process form data
-		formValues := url.Values{}
-		pairs := strings.Split(*formData, ",")
-		for _, pair := range pairs {
-			parts := strings.SplitN(pair, "=", 2)
-			if len(parts) == 2 {
-				formValues.Add(parts[0], parts[1])
+	}
+
+	// place the body, or one field of it, on the clipboard
+	if opts.copy || opts.copyField != "" {
+		clip := data
+		if opts.copyField != "" {
+			value, err := lookupJSON(data, opts.copyField)
+			if err != nil {
+				return nil, fmt.Errorf("selecting field to copy: %w", err)
 			}
+			clip = []byte(jsonText(value))
 		}
-		reqBody = strings.NewReader(formValues.Encode())
-		// Set form content type if not overridden
-		if req.Header.Get("Content-Type") == "" {
-			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if err := copyToClipboard(clip); err != nil {
+			fmt.Fprintln(w, loc.T("Error copying to clipboard: %v", err))
+		} else {
+			fmt.Fprintln(w, loc.T("Response copied to clipboard"))
 		}
-	} else {
-		reqBody = strings.NewReader(*body)
 	}
 
-	// build the request
-	req, err := http.NewRequest(*method, *url, reqBody)
+	// check -expect-header rules; failures are reported after the response
+	var assertErr error
+	resp.assertions, assertErr = checkAssertions(opts.headerRules, resp.Header)
+
+	printResponse(w, loc, opts.output, resp)
+	if assertErr != nil {
+		return resp.Response, assertErr
+	}
+
+	// fetch the related resource named by -follow-rel
+	if opts.followRel != "" {
+		return followLink(ctx, sess, resp)
+	}
+	return resp.Response, nil
+}
+
+// exchange builds the request described by the flags and sends it, trying
+// the -failover targets and waiting for a -poll-location job as asked. It
+// returns the request along with the final response.
+func exchange(ctx context.Context, sess *session) (*http.Request, *response, error) {
+	opts := sess.opts
+
+	// determine the request body
+	body, contentType, err := requestBody(opts)
 	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		os.Exit(1)
+		return nil, nil, err
 	}
 
-	if *username != "" {
-		req.SetBasicAuth(*username, *password)
+	// build the request
+	req, err := sess.newRequest(ctx, opts.method, opts.url, body)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// add headers if provided
-	if *headers != "" {
-		// Support both comma and newline separated headers
-		headerList := strings.Replace(*headers, "\n", ",", -1)
-		pairs := strings.Split(headerList, ",")
-		for _, pair := range pairs {
-			if pair = strings.TrimSpace(pair); pair == "" {
-				continue
-			}
-			parts := strings.SplitN(pair, ":", 2)
-			if len(parts) == 2 {
-				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-				req.Header.Set(key, value)
-			}
-		}
+	// Set the body's content type if not overridden
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
 	}
 
 	// Apply default Content-Type only if not already set
@@ -141,92 +229,276 @@ func main() {
 	}
 
 	// display request information in verbose mode
-	if *verbose {
-		fmt.Printf("\n> %s %s\n", req.Method, req.URL)
+	if opts.verbose {
+		fmt.Fprintf(sess.w, "\n> %s %s\n", req.Method, req.URL)
 		for key, values := range req.Header {
-			fmt.Printf("> %s: %s\n", key, strings.Join(values, ", "))
+			fmt.Fprintf(sess.w, "> %s: %s\n", key, strings.Join(values, ", "))
 		}
-		if *body != "" || *bodyFile != "" {
-			fmt.Println("> ")
-			fmt.Println("> " + *body)
+		if opts.body != "" || opts.bodyFile != "" {
+			fmt.Fprintln(sess.w, "> ")
+			fmt.Fprintln(sess.w, "> "+opts.body)
 		}
-		fmt.Println()
+		fmt.Fprintln(sess.w)
 	}
 
-	// Implement retries
-	var respData []byte
-	var finalResp *http.Response
-	var finalErr error
+	// send the request, moving on to the next -failover target on failure
+	var resp *response
+	if len(opts.failoverBases) > 0 {
+		resp, err = sendFailover(ctx, sess, req, opts.failoverBases)
+	} else {
+		resp, err = sess.send(ctx, req)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
 
-	for attempt := 0; attempt <= *retries; attempt++ {
-		if attempt > 0 {
-			fmt.Printf("Retry attempt %d/%d...\n", attempt, *retries)
-			time.Sleep(time.Duration(*retryDelay) * time.Second)
+	// wait for an asynchronous job to finish and continue with its result
+	if opts.pollLocation && resp.StatusCode == http.StatusAccepted {
+		if resp, err = pollJob(ctx, sess, resp); err != nil {
+			return nil, nil, err
 		}
+	}
+	return req, resp, nil
+}
 
-		startTime := time.Now()
-		resp, err := client.Do(req)
-		if err == nil {
-			defer resp.Body.Close()
-			data, err := io.ReadAll(resp.Body)
-			if err == nil {
-				duration = time.Since(startTime)
-				respData = data
-				finalResp = resp
-				finalErr = nil
-				break
-			}
-			finalErr = err
-		} else {
-			finalErr = err
-		}
+// followLink fetches the resource that resp links to with the -follow-rel
+// relation, reusing the headers of the request resp answered.
+func followLink(ctx context.Context, sess *session, resp *response) (*http.Response, error) {
+	opts := sess.opts
+	links := parseLinkHeader(resp.Header.Values("Link"))
+	target, ok := findLink(links, opts.followRel)
+	if !ok {
+		return nil, fmt.Errorf("no Link with rel=%q in response", opts.followRel)
+	}
+	next, err := resp.Request.URL.Parse(target.URL)
+	if err != nil {
+		return nil, fmt.Errorf("resolving link %q: %w", target.URL, err)
 	}
 
-	if finalErr != nil {
-		fmt.Printf("Error after %d attempts: %v\n", *retries+1, finalErr)
-		os.Exit(1)
+	relReq, err := http.NewRequestWithContext(ctx, http.MethodGet, next.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
+	relReq.Header = followUpHeader(resp.Request, next)
 
-	resp = finalResp
-	data := respData
+	if opts.verbose {
+		fmt.Fprintf(sess.w, "\n> %s %s (rel=%s)\n\n", relReq.Method, relReq.URL, opts.followRel)
+	}
 
-	duration := time.Since(startTime)
+	relResp, err := sess.send(ctx, relReq)
+	if err != nil {
+		return nil, err
+	}
+	if opts.output == "pretty" {
+		fmt.Fprintln(sess.w)
+	}
+	printResponse(sess.w, sess.loc, opts.output, relResp)
+	return relResp.Response, nil
+}
 
-	// Display timing stats in verbose mode
-	if *verbose {
-		fmt.Printf("\nRequest completed in %v\n", duration)
-		fmt.Printf("Time to first byte: %v\n", time.Since(startTime))
+// followUpHeader returns the headers for a GET to target made on behalf of
+// prev, such as following a link or polling a job. Like net/http does on
+// redirects, credentials and cookies are only passed along when target has
+// the same scheme, host and port as prev.
+func followUpHeader(prev *http.Request, target *url.URL) http.Header {
+	h := prev.Header.Clone()
+	h.Del("Content-Type")
+	if !sameOrigin(prev.URL, target) {
+		for _, name := range []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"} {
+			h.Del(name)
+		}
 	}
+	return h
+}
 
-	// response output
-	data, err = io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("Error reading response body: %v\n", err)
-		os.Exit(1)
+// sameOrigin reports whether a and b share scheme, host and port, with the
+// port defaulting to the scheme's.
+func sameOrigin(a, b *url.URL) bool {
+	origin := func(u *url.URL) string {
+		scheme, port := strings.ToLower(u.Scheme), u.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[scheme]
+		}
+		return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
 	}
+	return origin(a) == origin(b)
+}
 
-	if *outputFile != "" {
-		err := os.WriteFile(*outputFile, data, 0644)
+// requestBody builds the request body from -body-file, -json, -form or
+// -body, in that order of precedence, along with the content type it
+// implies (empty when the body's type is unknown).
+func requestBody(opts *options) (*payload, string, error) {
+	if opts.bodyFile != "" {
+		body, err := filePayload(opts.bodyFile)
 		if err != nil {
-			fmt.Printf("Error saving response to file: %v\n", err)
-		} else {
-			fmt.Printf("Response saved to %s\n", *outputFile)
+			return nil, "", fmt.Errorf("reading body file: %w", err)
+		}
+		return body, "", nil
+	} else if opts.jsonData != "" {
+		// Process JSON data from command line
+		jsonMap := make(map[string]interface{})
+		pairs := strings.Split(opts.jsonData, ",")
+		for _, pair := range pairs {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) == 2 {
+				jsonMap[parts[0]] = parts[1]
+			}
+		}
+		jsonBytes, err := json.Marshal(jsonMap)
+		if err != nil {
+			return nil, "", fmt.Errorf("creating JSON: %w", err)
+		}
+		return bytesPayload(jsonBytes), "application/json", nil
+	} else if opts.formData != "" {
+		// Process form data
+		formValues := url.Values{}
+		pairs := strings.Split(opts.formData, ",")
+		for _, pair := range pairs {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) == 2 {
+				formValues.Add(parts[0], parts[1])
+			}
+		}
+		return bytesPayload([]byte(formValues.Encode())), "application/x-www-form-urlencoded", nil
+	}
+	return bytesPayload([]byte(opts.body)), "", nil
+}
+
+// options holds the flags of the default request command.
+type options struct {
+	method         string
+	url            string
+	body           string
+	headers        string
+	timeout        int
+	output         string
+	outputFile     string
+	bodyFile       string
+	username       string
+	password       string
+	verbose        bool
+	noRedirect     bool
+	http2          bool
+	jsonData       string
+	formData       string
+	retries        int
+	retryDelay     int
+	followRel      string
+	script         string
+	vars           string
+	nonInteractive bool
+	copy           bool
+	copyField      string
+	notify         bool
+	bell           bool
+	auditLog       string
+	locale         string
+	expectHeaders  stringList
+	noThrottle     bool
+	retryIf        string
+	retryFor       int
+	pollLocation   bool
+	pollURLField   string
+	pollUntil      string
+	pollInterval   int
+	pollBackoff    float64
+	pollTimeout    int
+	failover       string
+
+	// parsed from expectHeaders, retryIf, pollUntil and failover by validate
+	headerRules   []*headerRule
+	retryCond     *condition
+	pollCond      *condition
+	failoverBases []*url.URL
+}
+
+// newRequestFlags registers the flags of the default request command. How
+// they are grouped in help and the man page is described by requestCommand.
+func newRequestFlags() (*flag.FlagSet, *options) {
+	opts := &options{}
+	fs := flag.NewFlagSet("rest-blazar", flag.ExitOnError)
+	fs.StringVar(&opts.method, "method", "GET", "HTTP method to use")
+	fs.StringVar(&opts.url, "url", "", "URL to send request to")
+	fs.StringVar(&opts.body, "body", "", "Body to send with request")
+	fs.StringVar(&opts.headers, "headers", "", "Headers to send with request")
+	fs.IntVar(&opts.timeout, "timeout", 10, "Timeout in seconds")
+	fs.StringVar(&opts.output, "output", "pretty", "Output format: pretty, json, headers-only, body-only")
+	fs.StringVar(&opts.outputFile, "save", "", "Save response body to file")
+	fs.StringVar(&opts.bodyFile, "body-file", "", "File containing the request body")
+	fs.StringVar(&opts.username, "user", "", "Username for basic auth")
+	fs.StringVar(&opts.password, "pass", "", "Password for basic auth")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Show request details")
+	fs.BoolVar(&opts.noRedirect, "no-redirect", false, "Don't follow redirects")
+	fs.BoolVar(&opts.http2, "http2", false, "Force HTTP/2 protocol")
+	fs.StringVar(&opts.jsonData, "json", "", "JSON data as key=value pairs (e.g. name=John,age=30)")
+	fs.StringVar(&opts.formData, "form", "", "Form data as key=value pairs (e.g. name=John,age=30)")
+	fs.IntVar(&opts.retries, "retries", 0, "Number of retry attempts for failed requests")
+	fs.IntVar(&opts.retryDelay, "retry-delay", 1, "Delay between retries in seconds")
+	fs.StringVar(&opts.followRel, "follow-rel", "", "Follow the Link header with this relation: next, self, alternate")
+	fs.StringVar(&opts.script, "script", "", "Run the requests listed in a script file (one \"METHOD URL [body-file]\" per line)")
+	fs.StringVar(&opts.vars, "vars", "", "Values for {{variables}} in the URL as key=value pairs (e.g. id=42,env=prod)")
+	fs.BoolVar(&opts.nonInteractive, "non-interactive", false, "Never prompt for missing variables or credentials (for CI)")
+	fs.BoolVar(&opts.copy, "copy", false, "Copy the response body to the clipboard")
+	fs.StringVar(&opts.copyField, "copy-field", "", "Copy only this field of a JSON body (e.g. data.token); implies -copy")
+	fs.BoolVar(&opts.notify, "notify", false, "Show a desktop notification when the request finishes")
+	fs.BoolVar(&opts.bell, "bell", false, "Ring the terminal bell when the request finishes")
+	fs.StringVar(&opts.auditLog, "audit-log", "", "Append a JSON line for every request made to this file (secrets redacted)")
+	fs.StringVar(&opts.locale, "locale", "", "Language and number format for messages (e.g. de-DE)")
+	fs.StringVar(&opts.retryIf, "retry-if", "", "Also retry while the response matches this expression (e.g. 'status==409 || body.state==\"pending\"')")
+	fs.IntVar(&opts.retryFor, "retry-for", 60, "Stop retrying -retry-if matches after this many seconds (0 for no limit)")
+	fs.BoolVar(&opts.pollLocation, "poll-location", false, "After a 202 Accepted, poll the job's Location until it finishes and print the result")
+	fs.StringVar(&opts.pollURLField, "poll-url-field", "", "Read the status URL from this field of the 202 body instead of Location (e.g. links.status)")
+	fs.StringVar(&opts.pollUntil, "poll-until", "", "Stop polling when the status response matches this expression (default: status is not 202)")
+	fs.IntVar(&opts.pollInterval, "poll-interval", 2, "Seconds to wait before the first poll")
+	fs.Float64Var(&opts.pollBackoff, "poll-backoff", 1.5, "Multiply the poll interval by this factor after every poll")
+	fs.IntVar(&opts.pollTimeout, "poll-timeout", 300, "Give up polling after this many seconds (0 for no limit)")
+	fs.StringVar(&opts.failover, "failover", "", "Base URLs to try in order when the request fails, times out or gets a 5xx (e.g. https://a,https://b)")
+	fs.BoolVar(&opts.noThrottle, "no-throttle", false, "Don't slow down or pause between requests for rate limit headers")
+	fs.Var(&opts.expectHeaders, "expect-header", "Fail unless a response header matches `rule`: Name, !Name, 'Name: value', 'Name: ~regexp', 'Name: >0' or 'Name: #2' (repeatable)")
+	return fs, opts
+}
+
+// setHeaders adds the headers given on the command line to req. Both comma
+// and newline separated "Key: Value" pairs are supported.
+func setHeaders(req *http.Request, headers string) {
+	for _, pair := range headerLines(headers) {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) == 2 {
+			key := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			req.Header.Set(key, value)
 		}
 	}
+}
 
-	switch *output {
+// headerLines splits the -headers value into "Key: Value" entries, which
+// are separated by commas or newlines.
+func headerLines(headers string) []string {
+	var lines []string
+	for _, pair := range strings.Split(strings.ReplaceAll(headers, "\n", ","), ",") {
+		if pair = strings.TrimSpace(pair); pair != "" {
+			lines = append(lines, pair)
+		}
+	}
+	return lines
+}
+
+// printResponse writes resp in the given output format. Only the pretty
+// format is localized; the others are meant for machines.
+func printResponse(w io.Writer, loc *localizer, output string, resp *response) {
+	switch output {
 	case "json":
-		outputJSON(resp, data, duration)
+		outputJSON(w, resp)
 	case "headers-only":
-		outputHeaders(resp)
+		outputHeaders(w, resp)
 	case "body-only":
-		fmt.Println(string(data))
+		fmt.Fprintln(w, string(resp.body))
 	default: // "pretty"
-		outputPretty(resp, data, duration)
+		outputPretty(w, loc, resp)
 	}
 }
 
-func outputPretty(resp *http.Response, data []byte, duration time.Duration) {
+func outputPretty(w io.Writer, loc *localizer, resp *response) {
 	// color codes for status
 	var statusColor string
 	switch {
@@ -243,34 +515,62 @@ func outputPretty(resp *http.Response, data []byte, duration time.Duration) {
 	}
 	resetColor := "\033[0m"
 
-	fmt.Printf("Status: %s%s%s\n", statusColor, resp.Status, resetColor)
-	fmt.Println("Headers:")
+	fmt.Fprintln(w, loc.T("Status: %s%s%s", statusColor, resp.Status, resetColor))
+	if resp.servedBy != "" {
+		fmt.Fprintln(w, loc.T("Served by: %s", resp.servedBy))
+	}
+	fmt.Fprintln(w, loc.T("Headers:"))
 	for key, values := range resp.Header {
-		fmt.Printf("  %s: %s\n", key, strings.Join(values, ", "))
+		fmt.Fprintf(w, "  %s: %s\n", key, strings.Join(values, ", "))
 	}
-	fmt.Println("Body:")
-	fmt.Println(string(data))
-	fmt.Printf("Request completed in %v\n", duration)
+	if links := parseLinkHeader(resp.Header.Values("Link")); len(links) > 0 {
+		fmt.Fprintln(w, loc.T("Links:"))
+		for _, l := range links {
+			fmt.Fprintf(w, "  %s\n", l)
+		}
+	}
+	fmt.Fprintln(w, loc.T("Body:"))
+	fmt.Fprintln(w, string(resp.body))
+	printAssertions(w, loc, resp.assertions)
+	fmt.Fprintln(w, loc.T("Request completed in %s", loc.Duration(resp.duration)))
 }
 
-func outputJSON(resp *http.Response, data []byte, duration time.Duration) {
+func outputJSON(w io.Writer, resp *response) {
+	jsonData, err := json.MarshalIndent(responseJSON(resp), "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "Error marshaling JSON response: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, string(jsonData))
+}
+
+// responseJSON is the -output json form of resp.
+func responseJSON(resp *response) map[string]interface{} {
 	result := map[string]interface{}{
 		"status":     resp.Status,
 		"statusCode": resp.StatusCode,
 		"headers":    resp.Header,
-		"body":       string(data),
-		"duration":   duration.String(),
+		"body":       string(resp.body),
+		"duration":   resp.duration.String(),
 	}
-	jsonData, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		fmt.Printf("Error marshaling JSON response: %v\n", err)
-		return
+	if resp.attempts != nil {
+		result["attempts"] = resp.attempts
+	}
+	if resp.assertions != nil {
+		result["assertions"] = resp.assertions
+	}
+	if resp.servedBy != "" {
+		result["servedBy"] = resp.servedBy
+		result["targets"] = resp.targets
+	}
+	if links := parseLinkHeader(resp.Header.Values("Link")); len(links) > 0 {
+		result["links"] = links
 	}
-	fmt.Println(string(jsonData))
+	return result
 }
 
-func outputHeaders(resp *http.Response) {
+func outputHeaders(w io.Writer, resp *response) {
 	for key, values := range resp.Header {
-		fmt.Printf("%s: %s\n", key, strings.Join(values, ", "))
+		fmt.Fprintf(w, "%s: %s\n", key, strings.Join(values, ", "))
 	}
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFollowUpHeader(t *testing.T) {
+	tests := []struct {
+		target string
+		keep   bool
+	}{
+		{"https://api.example.com/items?page=2", true},
+		{"https://API.example.com:443/jobs/1", true},
+		{"http://api.example.com/items", false},
+		{"https://api.example.com:8443/items", false},
+		{"https://evil.example.com/items", false},
+		{"https://example.com/items", false},
+	}
+	for _, tt := range tests {
+		prev, _ := http.NewRequest(http.MethodPost, "https://api.example.com/items", nil)
+		prev.SetBasicAuth("alice", "s3cret")
+		prev.Header.Set("Cookie", "session=1")
+		prev.Header.Set("Content-Type", "application/json")
+		prev.Header.Set("X-Trace", "abc")
+		target, _ := url.Parse(tt.target)
+
+		h := followUpHeader(prev, target)
+		if got := h.Get("Authorization") != "" && h.Get("Cookie") != ""; got != tt.keep {
+			t.Errorf("%s: credentials kept = %v, want %v", tt.target, got, tt.keep)
+		}
+		if h.Get("Content-Type") != "" {
+			t.Errorf("%s: Content-Type was carried over", tt.target)
+		}
+		if h.Get("X-Trace") != "abc" {
+			t.Errorf("%s: X-Trace was dropped", tt.target)
+		}
+		if prev.Header.Get("Authorization") == "" {
+			t.Errorf("%s: the original request lost its credentials", tt.target)
+		}
+	}
+}
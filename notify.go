@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// notifyCompletion tells the user a request has finished, with a desktop
+// notification and/or the terminal bell, so they can switch windows while
+// a slow call runs.
+func notifyCompletion(desktop, bell bool, subject, outcome string, duration time.Duration) {
+	if bell {
+		fmt.Fprint(os.Stderr, "\a")
+	}
+	if !desktop {
+		return
+	}
+	message := fmt.Sprintf("%s in %v", outcome, duration.Round(time.Millisecond))
+	if err := desktopNotification("rest-blazar: "+subject, message); err != nil {
+		// like the bell, kept apart from the response output
+		fmt.Fprintf(os.Stderr, "Error sending notification: %v\n", err)
+	}
+}
+
+func desktopNotification(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		// a balloon tip needs no extra modules and works on every supported version
+		script := `Add-Type -AssemblyName System.Windows.Forms;` +
+			`$n = New-Object System.Windows.Forms.NotifyIcon;` +
+			`$n.Icon = [System.Drawing.SystemIcons]::Information;` +
+			`$n.Visible = $true;` +
+			`$n.ShowBalloonTip(5000, $env:RB_TITLE, $env:RB_MESSAGE, 'Info');` +
+			`Start-Sleep -Seconds 5; $n.Dispose()`
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Env = append(os.Environ(), "RB_TITLE="+title, "RB_MESSAGE="+message)
+		// the balloon has to stay alive for a few seconds; don't wait for it
+		return cmd.Start()
+	default:
+		cmd = exec.Command("notify-send", "--app-name=rest-blazar", title, message)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v %s", cmd.Path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+)
+
+// payload is a request body that can be read any number of times, so
+// retries, 307/308 redirects and HTTP/2 replays resend the same bytes.
+// Small bodies are kept in memory; body files are reopened for every read
+// instead of being loaded up front.
+type payload struct {
+	data []byte
+	path string
+	size int64
+}
+
+func bytesPayload(data []byte) *payload {
+	return &payload{data: data, size: int64(len(data))}
+}
+
+// filePayload reads the body from path. Only regular files are reopened
+// for every read; pipes, /dev/stdin and the like report no size and can be
+// read once, so they are loaded into memory.
+func filePayload(path string) (*payload, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return bytesPayload(data), nil
+	}
+	return &payload{path: path, size: info.Size()}, nil
+}
+
+// open returns a fresh reader positioned at the start of the body.
+func (p *payload) open() (io.ReadCloser, error) {
+	if p.path != "" {
+		return os.Open(p.path)
+	}
+	return io.NopCloser(bytes.NewReader(p.data)), nil
+}
+
+// attach sets req's body to p, including GetBody so the transport and the
+// redirect logic can rewind it.
+func (p *payload) attach(req *http.Request) error {
+	body, err := p.open()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	req.GetBody = p.open
+	req.ContentLength = p.size
+	if p.size == 0 {
+		// matches http.NewRequest for an empty body
+		body.Close()
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+	}
+	return nil
+}
+
+// rewind returns a copy of req with a fresh body for another attempt. A
+// request without GetBody has no body to resend and is returned as is.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// pollJob waits for the long-running operation that accepted, a 202
+// response to req, stands for. The status URL comes from the Location
+// header or the -poll-url-field of the body, and is fetched until the
+// response is terminal: no longer 202 Accepted, or matching -poll-until.
+// The terminal response, with any redirect to the finished resource
+// followed, is returned. Polls reuse the headers of the accepted request,
+// without its credentials when the status URL is on another origin.
+func pollJob(ctx context.Context, sess *session, accepted *response) (*response, error) {
+	opts := sess.opts
+	target, err := statusURL(opts.pollURLField, accepted)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := time.Duration(opts.pollInterval) * time.Second
+	var deadline time.Time
+	if opts.pollTimeout > 0 {
+		deadline = time.Now().Add(time.Duration(opts.pollTimeout) * time.Second)
+	}
+	if opts.output == "pretty" || opts.verbose {
+		fmt.Fprintln(sess.w, sess.loc.T("Accepted; polling %s...", target))
+	}
+
+	last := accepted
+	for n := 1; ; n++ {
+		// the server's Retry-After wins over our own schedule
+		wait := interval
+		now := time.Now()
+		if rl, ok := parseRateLimit(last.Header, now); ok && rl.retryAfter.After(now) {
+			wait = rl.retryAfter.Sub(now)
+		}
+		if !deadline.IsZero() && now.Add(wait).After(deadline) {
+			return nil, fmt.Errorf("%s did not finish within %ds: %w", target, opts.pollTimeout, context.DeadlineExceeded)
+		}
+		if opts.verbose {
+			fmt.Fprintln(sess.w, sess.loc.T("* Waiting %s before poll %d", sess.loc.Duration(wait), n))
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		pollReq, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		pollReq.Header = followUpHeader(accepted.Request, target)
+
+		resp, err := sess.send(ctx, pollReq)
+		if err != nil {
+			return nil, fmt.Errorf("polling %s: %w", target, err)
+		}
+		done := resp.StatusCode != http.StatusAccepted
+		if opts.pollCond != nil {
+			done = opts.pollCond.match(resp)
+		}
+		if opts.verbose {
+			fmt.Fprintln(sess.w, sess.loc.T("* Poll %d: %s", n, resp.Status))
+		}
+		if done {
+			return resp, nil
+		}
+
+		// a job may move its status resource while it runs
+		if resp.StatusCode == http.StatusAccepted && resp.Header.Get("Location") != "" {
+			if next, err := statusURL("", resp); err == nil {
+				target = next
+			}
+		}
+		interval = time.Duration(float64(interval) * opts.pollBackoff)
+		last = resp
+	}
+}
+
+// statusURL finds where to poll the job described by resp: the JSON field
+// when one is given, otherwise the Location header. Relative references are
+// resolved against the URL resp came from.
+func statusURL(field string, resp *response) (*url.URL, error) {
+	var ref string
+	if field != "" {
+		value, err := lookupJSON(resp.body, field)
+		if err != nil {
+			return nil, fmt.Errorf("finding status URL: %w", err)
+		}
+		ref = jsonText(value)
+	} else if ref = resp.Header.Get("Location"); ref == "" {
+		return nil, fmt.Errorf("%s response has no Location header to poll; use -poll-url-field to read it from the body", resp.Status)
+	}
+	target, err := resp.Request.URL.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving status URL %q: %w", ref, err)
+	}
+	return target, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// varPattern matches {{name}} placeholders in a URL.
+var varPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// errNotInteractive is returned when input is needed but cannot be asked for.
+var errNotInteractive = errors.New("input required but prompting is disabled")
+
+// resolveVars replaces every {{name}} in s with its value from vars. Missing
+// values are asked for on the terminal when interactive is set, and are
+// remembered in vars so later requests reuse the answer.
+func resolveVars(s string, vars map[string]string, interactive bool) (string, error) {
+	var firstErr error
+	resolved := varPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := varPattern.FindStringSubmatch(m)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if firstErr != nil {
+			return m
+		}
+		if !interactive {
+			firstErr = fmt.Errorf("unresolved variable {{%s}} (set it with -vars): %w", name, errNotInteractive)
+			return m
+		}
+		value, err := prompt(fmt.Sprintf("Value for {{%s}}: ", name), false)
+		if err != nil {
+			firstErr = fmt.Errorf("reading {{%s}}: %w", name, err)
+			return m
+		}
+		vars[name] = value
+		return value
+	})
+	return resolved, firstErr
+}
+
+// resolveCredentials asks for whichever half of the basic auth pair is
+// missing once the other half has been given.
+func resolveCredentials(username, password *string, interactive bool) error {
+	if *username == "" && *password == "" {
+		return nil
+	}
+	if !interactive && (*username == "" || *password == "") {
+		return fmt.Errorf("basic auth needs both -user and -pass: %w", errNotInteractive)
+	}
+	var err error
+	if *username == "" {
+		if *username, err = prompt("Username: ", false); err != nil {
+			return err
+		}
+	}
+	if *password == "" {
+		if *password, err = prompt(fmt.Sprintf("Password for %s: ", *username), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prompt writes label to stderr and reads one line from the terminal. When
+// secret is set the typed characters are not echoed.
+func prompt(label string, secret bool) (string, error) {
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("stdin is not a terminal: %w", errNotInteractive)
+	}
+	fmt.Fprint(os.Stderr, label)
+	if secret {
+		restore, err := disableEcho()
+		if err != nil {
+			fmt.Fprintln(os.Stderr)
+			return "", fmt.Errorf("disabling terminal echo: %v", err)
+		}
+		defer fmt.Fprintln(os.Stderr)
+		defer restore()
+	}
+	return readLine(os.Stdin)
+}
+
+// readLine reads up to a newline one byte at a time, so nothing after the
+// line is consumed from r.
+func readLine(r io.Reader) (string, error) {
+	var b strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				break
+			}
+			b.WriteByte(buf[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(b.String(), "\r"), nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
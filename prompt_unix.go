@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// disableEcho turns off terminal echo for stdin and returns a function
+// that turns it back on.
+func disableEcho() (func(), error) {
+	if err := stty("-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty("echo") }, nil
+}
+
+func stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
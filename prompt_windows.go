@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// disableEcho clears ENABLE_ECHO_INPUT on the stdin console and returns a
+// function that restores the previous mode.
+func disableEcho() (func(), error) {
+	handle := syscall.Handle(os.Stdin.Fd())
+	var mode uint32
+	if r, _, err := procGetConsoleMode.Call(uintptr(handle), uintptr(unsafe.Pointer(&mode))); r == 0 {
+		return nil, err
+	}
+	if r, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode&^enableEchoInput)); r == 0 {
+		return nil, err
+	}
+	return func() { procSetConsoleMode.Call(uintptr(handle), uintptr(mode)) }, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateLimit is the request budget a server advertised in its last response,
+// through the RateLimit-* or X-RateLimit-* headers, the combined RateLimit
+// header or Retry-After.
+type rateLimit struct {
+	limit      int // -1 when not advertised
+	remaining  int // -1 when not advertised
+	reset      time.Time
+	retryAfter time.Time
+}
+
+// parseRateLimit reads the rate limit headers of h, reporting false when
+// there are none.
+func parseRateLimit(h http.Header, now time.Time) (rateLimit, bool) {
+	rl := rateLimit{limit: -1, remaining: -1}
+	found := false
+
+	for _, prefix := range []string{"X-Ratelimit-", "Ratelimit-"} {
+		if n, ok := leadingInt(h.Get(prefix + "Limit")); ok {
+			rl.limit, found = int(n), true
+		}
+		if n, ok := leadingInt(h.Get(prefix + "Remaining")); ok {
+			rl.remaining, found = int(n), true
+		}
+		if n, ok := leadingInt(h.Get(prefix + "Reset")); ok {
+			rl.reset, found = resetTime(n, now), true
+		}
+	}
+
+	// RateLimit: limit=100, remaining=50, reset=30 or "default";r=50;t=30
+	if v := h.Get("Ratelimit"); v != "" {
+		for _, param := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' }) {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.Atoi(strings.Trim(strings.TrimSpace(value), `"`))
+			if err != nil {
+				continue
+			}
+			switch strings.ToLower(key) {
+			case "limit":
+				rl.limit, found = n, true
+			case "remaining", "r":
+				rl.remaining, found = n, true
+			case "reset", "t":
+				rl.reset, found = now.Add(time.Duration(n)*time.Second), true
+			}
+		}
+	}
+
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			rl.retryAfter, found = now.Add(time.Duration(n)*time.Second), true
+		} else if t, err := http.ParseTime(v); err == nil {
+			rl.retryAfter, found = t, true
+		}
+	}
+	return rl, found
+}
+
+// leadingInt parses the number at the start of a header value such as
+// "100" or "100, 100;w=60".
+func leadingInt(v string) (int64, bool) {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexAny(v, ",;"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	return n, err == nil
+}
+
+// resetTime interprets a reset value, which some APIs send as seconds until
+// the reset and others as a Unix timestamp in seconds or milliseconds.
+func resetTime(n int64, now time.Time) time.Time {
+	switch {
+	case n > 1e12:
+		return time.UnixMilli(n)
+	case n > 1e9:
+		return time.Unix(n, 0)
+	}
+	return now.Add(time.Duration(n) * time.Second)
+}
+
+// wait reports how long to hold off before the next request. Retry-After
+// and an exhausted budget pause until the given time; once less than a tenth
+// of the budget is left, the remaining requests are spread out evenly over
+// the rest of the window.
+func (rl rateLimit) wait(now time.Time) (d time.Duration, paused bool) {
+	switch {
+	case rl.retryAfter.After(now):
+		return rl.retryAfter.Sub(now), true
+	case rl.remaining == 0 && rl.reset.After(now):
+		return rl.reset.Sub(now), true
+	case rl.limit > 0 && rl.remaining > 0 && rl.remaining*10 < rl.limit && rl.reset.After(now):
+		return rl.reset.Sub(now) / time.Duration(rl.remaining+1), false
+	}
+	return 0, false
+}
+
+// describe renders the budget for verbose output.
+func (rl rateLimit) describe(loc *localizer, now time.Time) string {
+	var parts []string
+	switch {
+	case rl.remaining >= 0 && rl.limit >= 0:
+		parts = append(parts, loc.T("%d of %d remaining", rl.remaining, rl.limit))
+	case rl.remaining >= 0:
+		parts = append(parts, loc.T("%d remaining", rl.remaining))
+	case rl.limit >= 0:
+		parts = append(parts, loc.T("limit %d", rl.limit))
+	}
+	if rl.reset.After(now) {
+		parts = append(parts, loc.T("resets in %s", loc.Duration(rl.reset.Sub(now).Round(time.Second))))
+	}
+	if rl.retryAfter.After(now) {
+		parts = append(parts, loc.T("retry after %s", loc.Duration(rl.retryAfter.Sub(now).Round(time.Second))))
+	}
+	return strings.Join(parts, ", ")
+}
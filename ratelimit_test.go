@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   rateLimit
+		found  bool
+	}{
+		{
+			name:   "none",
+			header: http.Header{"Content-Type": {"text/plain"}},
+			want:   rateLimit{limit: -1, remaining: -1},
+		},
+		{
+			name:   "x-ratelimit with seconds until reset",
+			header: http.Header{"X-Ratelimit-Limit": {"100"}, "X-Ratelimit-Remaining": {"42"}, "X-Ratelimit-Reset": {"30"}},
+			want:   rateLimit{limit: 100, remaining: 42, reset: now.Add(30 * time.Second)},
+			found:  true,
+		},
+		{
+			name:   "unix timestamp reset",
+			header: http.Header{"X-Ratelimit-Reset": {"1714568460"}},
+			want:   rateLimit{limit: -1, remaining: -1, reset: time.Unix(1714568460, 0)},
+			found:  true,
+		},
+		{
+			name:   "unix millisecond reset",
+			header: http.Header{"X-Ratelimit-Reset": {"1714568460500"}},
+			want:   rateLimit{limit: -1, remaining: -1, reset: time.UnixMilli(1714568460500)},
+			found:  true,
+		},
+		{
+			name:   "ratelimit fields with policy suffix",
+			header: http.Header{"Ratelimit-Limit": {"100, 100;w=60"}, "Ratelimit-Remaining": {"0"}},
+			want:   rateLimit{limit: 100, remaining: 0},
+			found:  true,
+		},
+		{
+			name:   "combined header",
+			header: http.Header{"Ratelimit": {"limit=10, remaining=5, reset=20"}},
+			want:   rateLimit{limit: 10, remaining: 5, reset: now.Add(20 * time.Second)},
+			found:  true,
+		},
+		{
+			name:   "structured combined header",
+			header: http.Header{"Ratelimit": {`"default";r=3;t=7`}},
+			want:   rateLimit{limit: -1, remaining: 3, reset: now.Add(7 * time.Second)},
+			found:  true,
+		},
+		{
+			name:   "retry-after seconds",
+			header: http.Header{"Retry-After": {"120"}},
+			want:   rateLimit{limit: -1, remaining: -1, retryAfter: now.Add(2 * time.Minute)},
+			found:  true,
+		},
+		{
+			name:   "retry-after date",
+			header: http.Header{"Retry-After": {"Wed, 01 May 2024 12:05:00 GMT"}},
+			want:   rateLimit{limit: -1, remaining: -1, retryAfter: now.Add(5 * time.Minute)},
+			found:  true,
+		},
+		{
+			name:   "unparsable values",
+			header: http.Header{"X-Ratelimit-Limit": {"lots"}, "Retry-After": {"soon"}},
+			want:   rateLimit{limit: -1, remaining: -1},
+		},
+	}
+	for _, tt := range tests {
+		got, found := parseRateLimit(tt.header, now)
+		if found != tt.found || got.limit != tt.want.limit || got.remaining != tt.want.remaining ||
+			!got.reset.Equal(tt.want.reset) || !got.retryAfter.Equal(tt.want.retryAfter) {
+			t.Errorf("%s: got %+v, %v; want %+v, %v", tt.name, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		rl     rateLimit
+		wait   time.Duration
+		paused bool
+	}{
+		{"plenty left", rateLimit{limit: 100, remaining: 50, reset: now.Add(time.Minute)}, 0, false},
+		{"retry-after", rateLimit{limit: -1, remaining: -1, retryAfter: now.Add(3 * time.Second)}, 3 * time.Second, true},
+		{"exhausted", rateLimit{limit: 100, remaining: 0, reset: now.Add(10 * time.Second)}, 10 * time.Second, true},
+		{"spread out", rateLimit{limit: 100, remaining: 4, reset: now.Add(10 * time.Second)}, 2 * time.Second, false},
+		{"reset passed", rateLimit{limit: 100, remaining: 0, reset: now.Add(-time.Second)}, 0, false},
+	}
+	for _, tt := range tests {
+		if wait, paused := tt.rl.wait(now); wait != tt.wait || paused != tt.paused {
+			t.Errorf("%s: got %v, %v; want %v, %v", tt.name, wait, paused, tt.wait, tt.paused)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// response is a completed exchange: the response with its fully read body,
+// how long the successful attempt took and, when retries are enabled, the
+// outcome of every attempt.
+type response struct {
+	*http.Response
+	body     []byte
+	duration time.Duration
+	attempts []attempt
+
+	// outcome of the -expect-header rules, if any
+	assertions []assertionResult
+
+	// with -failover, the target that answered and how each one fared
+	servedBy string
+	targets  []targetResult
+}
+
+// attempt records one try of a request.
+type attempt struct {
+	Number     int    `json:"attempt"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Duration   string `json:"duration"`
+	Backoff    string `json:"backoff,omitempty"`
+	RetryIf    bool   `json:"retryIfMatched,omitempty"`
+}
+
+// retryPolicy controls how send retries failed attempts.
+type retryPolicy struct {
+	retries int
+	delay   time.Duration
+	log     io.Writer // retry notices; nil for none
+	loc     *localizer
+	verbose bool // also report the outcome of every attempt
+
+	// retryIf also retries responses it matches, up to retries times (when
+	// set) and while the next attempt would start before deadline (when set)
+	retryIf  *condition
+	deadline time.Time
+}
+
+// errRetryCondition is returned when a response still matched -retry-if
+// once no retries were left.
+var errRetryCondition = errors.New("-retry-if still matched")
+
+// send performs the request, retrying failed attempts and responses that
+// match the retry condition, and returns the response along with its fully
+// read body. Every retry resends the body through req.GetBody, and waiting
+// between attempts stops early when ctx is cancelled.
+func send(ctx context.Context, client *http.Client, req *http.Request, policy retryPolicy) (*response, error) {
+	log, loc := policy.log, policy.loc
+	if log == nil {
+		log = io.Discard
+	}
+	req = req.WithContext(ctx)
+	record := policy.retries > 0 || policy.retryIf != nil
+
+	var attempts []attempt
+	var backoff time.Duration
+	for n := 0; ; n++ {
+		attemptReq := req
+		if n > 0 {
+			if policy.retries > 0 {
+				fmt.Fprintln(log, loc.T("Retry attempt %d/%d...", n, policy.retries))
+			} else {
+				fmt.Fprintln(log, loc.T("Retry attempt %d...", n))
+			}
+			if policy.verbose {
+				fmt.Fprintln(log, loc.T("* Waiting %s before attempt %d", loc.Duration(backoff), n+1))
+			}
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			var err error
+			if attemptReq, err = rewind(req); err != nil {
+				return nil, fmt.Errorf("rewinding request body: %w", err)
+			}
+		}
+
+		startTime := time.Now()
+		resp, data, err := roundTrip(client, attemptReq)
+		elapsed := time.Since(startTime)
+
+		var result *response
+		matched := false
+		a := attempt{Number: n + 1, Duration: elapsed.String()}
+		if backoff > 0 {
+			a.Backoff = backoff.String()
+		}
+		backoff = policy.backoff(resp)
+		if err != nil {
+			a.Error = err.Error()
+		} else {
+			a.StatusCode = resp.StatusCode
+			result = &response{Response: resp, body: data, duration: elapsed}
+			matched = policy.retryIf != nil && policy.retryIf.match(result)
+			a.RetryIf = matched
+		}
+		if record {
+			attempts = append(attempts, a)
+			if policy.verbose {
+				outcome := a.Error
+				if err == nil {
+					outcome = resp.Status
+				}
+				if matched {
+					outcome = loc.T("%s, -retry-if matched", outcome)
+				}
+				if policy.retries > 0 {
+					fmt.Fprintln(log, loc.T("* Attempt %d/%d: %s (%s)", n+1, policy.retries+1, outcome, loc.Duration(elapsed)))
+				} else {
+					fmt.Fprintln(log, loc.T("* Attempt %d: %s (%s)", n+1, outcome, loc.Duration(elapsed)))
+				}
+			}
+		}
+
+		switch {
+		case err != nil:
+			if n < policy.retries {
+				continue
+			}
+			if n > 0 {
+				return nil, fmt.Errorf("after %d attempts: %w", n+1, err)
+			}
+			return nil, err
+		case matched:
+			if policy.canPoll(n, backoff) {
+				continue
+			}
+			return nil, fmt.Errorf("%w after %d attempts", errRetryCondition, n+1)
+		}
+		result.attempts = attempts
+		return result, nil
+	}
+}
+
+// backoff is how long to wait before retrying after resp, which is nil for a
+// network error: -retry-delay, or longer when the server asked for it with
+// Retry-After, as it does along with a 429 or 503.
+func (p retryPolicy) backoff(resp *http.Response) time.Duration {
+	if resp == nil {
+		return p.delay
+	}
+	now := time.Now()
+	if rl, ok := parseRateLimit(resp.Header, now); ok && rl.retryAfter.After(now) {
+		return max(p.delay, rl.retryAfter.Sub(now))
+	}
+	return p.delay
+}
+
+// canPoll reports whether a response matching the retry condition after
+// attempt n (counting from 0) may be retried after waiting backoff.
+func (p retryPolicy) canPoll(n int, backoff time.Duration) bool {
+	if p.retries > 0 && n >= p.retries {
+		return false
+	}
+	return p.deadline.IsZero() || time.Now().Add(backoff).Before(p.deadline)
+}
+
+// roundTrip sends req and reads the whole response body.
+func roundTrip(client *http.Client, req *http.Request) (*http.Response, []byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+// robotsGroup is a set of rules that applies to one or more user agents.
+type robotsGroup struct {
+	UserAgents []string     `json:"userAgents"`
+	Rules      []robotsRule `json:"rules,omitempty"`
+	CrawlDelay string       `json:"crawlDelay,omitempty"`
+}
+
+// robotsFile is a parsed robots.txt file.
+type robotsFile struct {
+	URL      string        `json:"url"`
+	Groups   []robotsGroup `json:"groups"`
+	Sitemaps []string      `json:"sitemaps,omitempty"`
+}
+
+// robotsOptions holds the flags of the robots command.
+type robotsOptions struct {
+	output  string
+	timeout int
+	agent   string
+}
+
+func newRobotsFlags() (*flag.FlagSet, *robotsOptions) {
+	opts := &robotsOptions{}
+	fs := flag.NewFlagSet("robots", flag.ExitOnError)
+	fs.StringVar(&opts.output, "output", "pretty", "Output format: pretty, json")
+	fs.IntVar(&opts.timeout, "timeout", 10, "Timeout in seconds")
+	fs.StringVar(&opts.agent, "agent", "", "Only show groups that apply to this user agent")
+	return fs, opts
+}
+
+// runRobots implements "rest-blazar robots [flags] host".
+func runRobots(ctx context.Context, args []string) {
+	fs, opts := newRobotsFlags()
+	fs.Usage = func() { printCommandHelp(fs.Output(), robotsCommand) }
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	// errors follow -output like those of a request, with the same exit codes
+	fail := func(err error) {
+		os.Exit(exitCode(os.Stdout, nil, opts.output, err))
+	}
+
+	target, err := siteURL(fs.Arg(0), "/robots.txt")
+	if err != nil {
+		fail(fmt.Errorf("parsing host: %w", err))
+	}
+
+	client := &http.Client{Timeout: time.Duration(opts.timeout) * time.Second}
+	data, err := fetchSiteFile(ctx, client, target)
+	if err != nil {
+		fail(fmt.Errorf("fetching robots.txt: %w", err))
+	}
+
+	robots := parseRobots(data)
+	robots.URL = target
+	if opts.agent != "" {
+		robots.Groups = robots.groupsFor(opts.agent)
+	}
+
+	if opts.output == "json" {
+		jsonData, err := json.MarshalIndent(robots, "", "  ")
+		if err != nil {
+			fail(fmt.Errorf("marshaling JSON response: %w", err))
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	fmt.Printf("Robots: %s\n", robots.URL)
+	for _, g := range robots.Groups {
+		fmt.Printf("User-agent: %s\n", strings.Join(g.UserAgents, ", "))
+		if g.CrawlDelay != "" {
+			fmt.Printf("  Crawl-delay: %s\n", g.CrawlDelay)
+		}
+		for _, r := range g.Rules {
+			fmt.Printf("  %-8s  %s\n", r.Type, r.Path)
+		}
+	}
+	if len(robots.Sitemaps) > 0 {
+		fmt.Println("Sitemaps:")
+		for _, s := range robots.Sitemaps {
+			fmt.Printf("  %s\n", s)
+		}
+	}
+}
+
+// parseRobots parses robots.txt content. Consecutive User-agent lines open a
+// shared group; Sitemap lines are collected regardless of position.
+func parseRobots(data []byte) robotsFile {
+	var robots robotsFile
+	var group *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				robots.Groups = append(robots.Groups, robotsGroup{})
+				group = &robots.Groups[len(robots.Groups)-1]
+			}
+			group.UserAgents = append(group.UserAgents, value)
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			if group != nil {
+				ruleType := "Allow"
+				if key == "disallow" {
+					ruleType = "Disallow"
+				}
+				group.Rules = append(group.Rules, robotsRule{Type: ruleType, Path: value})
+			}
+		case "crawl-delay":
+			if group != nil {
+				group.CrawlDelay = value
+			}
+		case "sitemap":
+			robots.Sitemaps = append(robots.Sitemaps, value)
+		}
+		inAgents = false
+	}
+	return robots
+}
+
+// groupsFor returns the groups that apply to agent, falling back to the
+// wildcard group when no group names it explicitly.
+func (r robotsFile) groupsFor(agent string) []robotsGroup {
+	agent = strings.ToLower(agent)
+	var matched, wildcard []robotsGroup
+	for _, g := range r.Groups {
+		for _, ua := range g.UserAgents {
+			ua = strings.ToLower(ua)
+			if ua == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+			if strings.Contains(agent, ua) {
+				matched = append(matched, g)
+				break
+			}
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+	return wildcard
+}
+
+// siteURL turns a bare host or URL into the address of a well-known file
+// on that host. A URL that already has a path other than "/" is kept as is.
+func siteURL(host, path string) (string, error) {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("missing host in %q", host)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = path
+	}
+	return u.String(), nil
+}
+
+// fetchSiteFile GETs target and fails on any non-2xx status.
+func fetchSiteFile(ctx context.Context, client *http.Client, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, req, retryPolicy{})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return resp.body, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testRobots = `# example
+User-agent: Googlebot
+User-agent: Bingbot
+Disallow: /private # not for crawlers
+Allow: /private/ok
+
+Sitemap: https://example.com/sitemap.xml
+
+user-agent: *
+crawl-delay: 5
+disallow: /
+Allow:
+`
+
+func TestParseRobots(t *testing.T) {
+	want := robotsFile{
+		Groups: []robotsGroup{
+			{
+				UserAgents: []string{"Googlebot", "Bingbot"},
+				Rules:      []robotsRule{{Type: "Disallow", Path: "/private"}, {Type: "Allow", Path: "/private/ok"}},
+			},
+			{
+				UserAgents: []string{"*"},
+				Rules:      []robotsRule{{Type: "Disallow", Path: "/"}, {Type: "Allow", Path: ""}},
+				CrawlDelay: "5",
+			},
+		},
+		Sitemaps: []string{"https://example.com/sitemap.xml"},
+	}
+	if got := parseRobots([]byte(testRobots)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRobots:\n got %+v\nwant %+v", got, want)
+	}
+
+	// rules before any User-agent line belong to no group
+	if got := parseRobots([]byte("Disallow: /\nSitemap: /s.xml\n")); len(got.Groups) != 0 || len(got.Sitemaps) != 1 {
+		t.Errorf("parseRobots without groups = %+v", got)
+	}
+}
+
+func TestGroupsFor(t *testing.T) {
+	robots := parseRobots([]byte(testRobots))
+	tests := []struct {
+		agent string
+		want  [][]string
+	}{
+		{"Googlebot", [][]string{{"Googlebot", "Bingbot"}}},
+		{"Mozilla/5.0 (compatible; bingbot/2.0)", [][]string{{"Googlebot", "Bingbot"}}},
+		{"curl", [][]string{{"*"}}},
+	}
+	for _, tt := range tests {
+		var got [][]string
+		for _, g := range robots.groupsFor(tt.agent) {
+			got = append(got, g.UserAgents)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("groupsFor(%q) = %v, want %v", tt.agent, got, tt.want)
+		}
+	}
+
+	if got := parseRobots([]byte("User-agent: Googlebot\nDisallow: /\n")).groupsFor("curl"); got != nil {
+		t.Errorf("groupsFor without a wildcard group = %+v, want none", got)
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	tests := []struct {
+		host, want string
+		err        bool
+	}{
+		{"example.com", "https://example.com/robots.txt", false},
+		{"example.com:8443", "https://example.com:8443/robots.txt", false},
+		{"http://example.com", "http://example.com/robots.txt", false},
+		{"http://example.com/", "http://example.com/robots.txt", false},
+		{"https://example.com/custom/robots.txt", "https://example.com/custom/robots.txt", false},
+		{"http://", "", true},
+		{"https://exa mple.com", "", true},
+	}
+	for _, tt := range tests {
+		got, err := siteURL(tt.host, "/robots.txt")
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("siteURL(%q) = %q, %v; want %q, error %v", tt.host, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// scriptStep is one line of a script file.
+type scriptStep struct {
+	Line     int    `json:"line"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	BodyFile string `json:"bodyFile,omitempty"`
+}
+
+// scriptResult is the outcome of a step, used for the final summary.
+type scriptResult struct {
+	scriptStep
+	StatusCode int    `json:"statusCode,omitempty"`
+	Duration   string `json:"duration"`
+	Bytes      int    `json:"bytes"`
+	Error      string `json:"error,omitempty"`
+	Category   string `json:"errorCategory,omitempty"`
+
+	// with -output json the response is part of the summary, so the whole
+	// run prints a single JSON document
+	Response map[string]interface{} `json:"response,omitempty"`
+
+	elapsed time.Duration
+}
+
+func (r scriptResult) failed() bool {
+	return r.Error != "" || r.StatusCode >= 400
+}
+
+// parseScript reads a script file. Each non-empty line is
+// "METHOD URL [body-file]"; lines starting with # are comments. Body file
+// paths are resolved relative to the script's directory.
+func parseScript(path string) ([]scriptStep, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var steps []scriptStep
+	dir := filepath.Dir(path)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected \"METHOD URL [body-file]\"", path, n)
+		}
+		step := scriptStep{Line: n, Method: strings.ToUpper(fields[0]), URL: fields[1]}
+		if len(fields) == 3 {
+			step.BodyFile = fields[2]
+			if !filepath.IsAbs(step.BodyFile) {
+				step.BodyFile = filepath.Join(dir, step.BodyFile)
+			}
+		}
+		steps = append(steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// errScriptFailed is returned by runScript when any step failed; the
+// summary has already been printed by then.
+var errScriptFailed = errors.New("script had failing requests")
+
+// runScript executes every step of the script in order and prints a summary.
+func runScript(ctx context.Context, sess *session, path string) error {
+	steps, err := parseScript(path)
+	if err != nil {
+		return fmt.Errorf("reading script: %w", err)
+	}
+
+	w := sess.w
+	output := sess.opts.output
+	results := make([]scriptResult, 0, len(steps))
+	for i, step := range steps {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if output == "pretty" {
+			fmt.Fprintf(w, "=== [%d/%d] %s %s\n", i+1, len(steps), step.Method, step.URL)
+		}
+		result := sess.runStep(ctx, step)
+		if result.Error != "" && output != "json" {
+			fmt.Fprintln(w, sess.loc.T("Error: %s", result.Error))
+		}
+		results = append(results, result)
+		if output == "pretty" {
+			fmt.Fprintln(w)
+		}
+	}
+
+	if !printScriptSummary(w, sess.loc, output, results) {
+		return errScriptFailed
+	}
+	return nil
+}
+
+func (sess *session) runStep(ctx context.Context, step scriptStep) scriptResult {
+	result := scriptResult{scriptStep: step}
+
+	var body *payload
+	if step.BodyFile != "" {
+		var err error
+		if body, err = filePayload(step.BodyFile); err != nil {
+			result.Error = fmt.Sprintf("reading body file: %v", err)
+			return result
+		}
+	}
+
+	req, err := sess.newRequest(ctx, step.Method, step.URL, body)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if sess.opts.verbose {
+		fmt.Fprintf(sess.w, "> %s %s\n", req.Method, req.URL)
+		for key, values := range req.Header {
+			fmt.Fprintf(sess.w, "> %s: %s\n", key, strings.Join(values, ", "))
+		}
+		fmt.Fprintln(sess.w)
+	}
+
+	resp, err := sess.send(ctx, req)
+	if err != nil {
+		result.Error = err.Error()
+		result.Category, _ = errorCategory(err)
+		return result
+	}
+	result.StatusCode = resp.StatusCode
+	result.Duration = resp.duration.String()
+	result.elapsed = resp.duration
+	result.Bytes = len(resp.body)
+
+	resp.assertions, err = checkAssertions(sess.opts.headerRules, resp.Header)
+	if err != nil {
+		result.Error = err.Error()
+		result.Category, _ = errorCategory(err)
+	}
+
+	if sess.opts.output == "json" {
+		result.Response = responseJSON(resp)
+	} else {
+		printResponse(sess.w, sess.loc, sess.opts.output, resp)
+	}
+	return result
+}
+
+// printScriptSummary prints one line per step followed by totals and
+// reports whether every step succeeded.
+func printScriptSummary(w io.Writer, loc *localizer, output string, results []scriptResult) bool {
+	failed := 0
+	for _, r := range results {
+		if r.failed() {
+			failed++
+		}
+	}
+
+	if output == "json" {
+		summary := map[string]interface{}{
+			"steps":     results,
+			"total":     len(results),
+			"succeeded": len(results) - failed,
+			"failed":    failed,
+		}
+		jsonData, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			fmt.Fprintf(w, "Error marshaling JSON response: %v\n", err)
+			return false
+		}
+		fmt.Fprintln(w, string(jsonData))
+		return failed == 0
+	}
+
+	fmt.Fprintln(w, loc.T("Summary:"))
+	for _, r := range results {
+		mark := "ok  "
+		if r.failed() {
+			mark = "FAIL"
+		}
+		status := fmt.Sprint(r.StatusCode)
+		if r.StatusCode == 0 {
+			status = "ERR"
+		}
+		fmt.Fprintf(w, "  %s  line %-3d  %-6s %-4s %10s %10s  %s\n", mark, r.Line, r.Method, status,
+			loc.Duration(r.elapsed), loc.Size(int64(r.Bytes)), r.URL)
+	}
+	fmt.Fprintln(w, loc.T("%d requests, %d succeeded, %d failed", len(results), len(results)-failed, failed))
+	return failed == 0
+}
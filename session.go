@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+)
+
+// session is the state shared by every request of one invocation: a client
+// (with its cookie jar and connection pool), the parsed flags, the resolved
+// {{variables}}, the last rate limit each host reported, and the writer and
+// language all output goes to.
+type session struct {
+	client *http.Client
+	opts   *options
+	vars   map[string]string
+	limits map[string]rateLimit
+	loc    *localizer
+	w      io.Writer
+}
+
+// newSession builds the HTTP client described by opts. A non-nil transport
+// is used instead of a new one, so its idle connections are reused.
+func newSession(opts *options, loc *localizer, w io.Writer, transport http.RoundTripper) (*session, error) {
+	// create http client with custom settings
+	client := &http.Client{
+		Timeout:   time.Duration(opts.timeout) * time.Second,
+		Transport: transport,
+	}
+
+	// Configure HTTP/2 transport if requested
+	if opts.http2 && transport == nil {
+		transport := &http.Transport{
+			ForceAttemptHTTP2: true,
+		}
+		client.Transport = transport
+	}
+
+	// configure redirect policy
+	if opts.noRedirect {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	// record every request in the audit log
+	if opts.auditLog != "" {
+		audit, err := newAuditTransport(client.Transport, opts.auditLog)
+		if err != nil {
+			return nil, fmt.Errorf("opening audit log: %w", err)
+		}
+		client.Transport = audit
+	}
+
+	// cookies set by one response are sent with the following requests
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating cookie jar: %w", err)
+	}
+	client.Jar = jar
+
+	vars := make(map[string]string)
+	for _, pair := range strings.Split(opts.vars, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) == 2 {
+			vars[strings.TrimSpace(parts[0])] = parts[1]
+		}
+	}
+
+	return &session{client: client, opts: opts, vars: vars, limits: make(map[string]rateLimit), loc: loc, w: w}, nil
+}
+
+// close releases what the session opened, such as the audit log.
+func (s *session) close() {
+	if audit, ok := s.client.Transport.(*auditTransport); ok {
+		audit.Close()
+	}
+}
+
+// newRequest builds a request to rawURL with the session's variables,
+// credentials and headers applied. A nil body sends no payload.
+func (s *session) newRequest(ctx context.Context, method, rawURL string, body *payload) (*http.Request, error) {
+	target, err := resolveVars(rawURL, s.vars, !s.opts.nonInteractive)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		if err := body.attach(req); err != nil {
+			return nil, fmt.Errorf("opening request body: %w", err)
+		}
+	}
+
+	if s.opts.username != "" {
+		req.SetBasicAuth(s.opts.username, s.opts.password)
+	}
+
+	// add headers if provided
+	setHeaders(req, s.opts.headers)
+	return req, nil
+}
+
+// send performs req with the session's client and retry settings. Unless
+// -no-throttle is set, it first waits as long as the rate limit reported by
+// the previous response from the same host asks for, so a -failover target
+// or script step on another host is not held up.
+func (s *session) send(ctx context.Context, req *http.Request) (*response, error) {
+	if err := s.throttle(ctx, req.URL.Host); err != nil {
+		return nil, err
+	}
+	policy := retryPolicy{
+		retries: s.opts.retries,
+		delay:   time.Duration(s.opts.retryDelay) * time.Second,
+		loc:     s.loc,
+		verbose: s.opts.verbose,
+		retryIf: s.opts.retryCond,
+	}
+	// retry notices would break up -output json and the other formats
+	if s.opts.output == "pretty" || s.opts.verbose {
+		policy.log = s.w
+	}
+	if policy.retryIf != nil && s.opts.retryFor > 0 {
+		policy.deadline = time.Now().Add(time.Duration(s.opts.retryFor) * time.Second)
+	}
+	resp, err := send(ctx, s.client, req, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// remember the budget for the next request
+	now := time.Now()
+	if rl, ok := parseRateLimit(resp.Header, now); ok {
+		s.limits[req.URL.Host] = rl
+		if s.opts.verbose {
+			fmt.Fprintln(s.w, s.loc.T("* Rate limit: %s", rl.describe(s.loc, now)))
+		}
+	} else {
+		delete(s.limits, req.URL.Host)
+	}
+	return resp, nil
+}
+
+// throttle sleeps until the rate limit of host allows another request.
+func (s *session) throttle(ctx context.Context, host string) error {
+	limit, ok := s.limits[host]
+	if !ok || s.opts.noThrottle {
+		return nil
+	}
+	d, paused := limit.wait(time.Now())
+	if d <= 0 {
+		return nil
+	}
+	switch {
+	case paused && (s.opts.output == "pretty" || s.opts.verbose):
+		fmt.Fprintln(s.w, s.loc.T("Rate limit reached, pausing %s...", s.loc.Duration(d.Round(time.Second))))
+	case !paused && s.opts.verbose:
+		fmt.Fprintln(s.w, s.loc.T("* Rate limit almost used up, waiting %s", s.loc.Duration(d.Round(time.Millisecond))))
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
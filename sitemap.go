@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// sitemapURL is a <url> entry of a urlset sitemap.
+type sitemapURL struct {
+	Loc        string `xml:"loc" json:"loc"`
+	LastMod    string `xml:"lastmod" json:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq" json:"changefreq,omitempty"`
+	Priority   string `xml:"priority" json:"priority,omitempty"`
+}
+
+// sitemapRef is a <sitemap> entry of a sitemap index.
+type sitemapRef struct {
+	Loc     string `xml:"loc" json:"loc"`
+	LastMod string `xml:"lastmod" json:"lastmod,omitempty"`
+}
+
+// sitemapFile is a parsed sitemap.xml, either a urlset or a sitemap index.
+type sitemapFile struct {
+	XMLName  xml.Name     `json:"-"`
+	URL      string       `xml:"-" json:"url"`
+	URLs     []sitemapURL `xml:"url" json:"urls,omitempty"`
+	Sitemaps []sitemapRef `xml:"sitemap" json:"sitemaps,omitempty"`
+}
+
+// sitemapOptions holds the flags of the sitemap command.
+type sitemapOptions struct {
+	output  string
+	timeout int
+	expand  bool
+}
+
+func newSitemapFlags() (*flag.FlagSet, *sitemapOptions) {
+	opts := &sitemapOptions{}
+	fs := flag.NewFlagSet("sitemap", flag.ExitOnError)
+	fs.StringVar(&opts.output, "output", "pretty", "Output format: pretty, json")
+	fs.IntVar(&opts.timeout, "timeout", 10, "Timeout in seconds")
+	fs.BoolVar(&opts.expand, "expand", false, "Fetch the sitemaps listed in a sitemap index and merge their URLs")
+	return fs, opts
+}
+
+// runSitemap implements "rest-blazar sitemap [flags] host-or-url".
+func runSitemap(ctx context.Context, args []string) {
+	fs, opts := newSitemapFlags()
+	fs.Usage = func() { printCommandHelp(fs.Output(), sitemapCommand) }
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	// errors follow -output like those of a request, with the same exit codes
+	fail := func(err error) {
+		os.Exit(exitCode(os.Stdout, nil, opts.output, err))
+	}
+
+	target, err := siteURL(fs.Arg(0), "/sitemap.xml")
+	if err != nil {
+		fail(fmt.Errorf("parsing host: %w", err))
+	}
+
+	client := &http.Client{Timeout: time.Duration(opts.timeout) * time.Second}
+	sitemap, err := fetchSitemap(ctx, client, target)
+	if err != nil {
+		fail(fmt.Errorf("fetching sitemap: %w", err))
+	}
+
+	if opts.expand {
+		for _, ref := range sitemap.Sitemaps {
+			child, err := fetchSitemap(ctx, client, ref.Loc)
+			if err != nil {
+				fail(fmt.Errorf("fetching sitemap: %w", err))
+			}
+			sitemap.URLs = append(sitemap.URLs, child.URLs...)
+		}
+		sitemap.Sitemaps = nil
+	}
+
+	if opts.output == "json" {
+		jsonData, err := json.MarshalIndent(sitemap, "", "  ")
+		if err != nil {
+			fail(fmt.Errorf("marshaling JSON response: %w", err))
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	fmt.Printf("Sitemap: %s\n", sitemap.URL)
+	if len(sitemap.Sitemaps) > 0 {
+		fmt.Printf("Sitemaps (%d):\n", len(sitemap.Sitemaps))
+		for _, ref := range sitemap.Sitemaps {
+			fmt.Printf("  %s\n", ref.Loc)
+		}
+	}
+	if len(sitemap.URLs) > 0 {
+		fmt.Printf("URLs (%d):\n", len(sitemap.URLs))
+		for _, u := range sitemap.URLs {
+			if u.LastMod != "" {
+				fmt.Printf("  %s  (lastmod %s)\n", u.Loc, u.LastMod)
+			} else {
+				fmt.Printf("  %s\n", u.Loc)
+			}
+		}
+	}
+}
+
+// fetchSitemap downloads and parses a sitemap, transparently handling
+// gzip-compressed files.
+func fetchSitemap(ctx context.Context, client *http.Client, target string) (*sitemapFile, error) {
+	data, err := fetchSiteFile(ctx, client, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// gzip magic number; servers often send .xml.gz without Content-Encoding
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data, err = io.ReadAll(zr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sitemap sitemapFile
+	if err := xml.Unmarshal(data, &sitemap); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", target, err)
+	}
+	if name := sitemap.XMLName.Local; name != "urlset" && name != "sitemapindex" {
+		return nil, fmt.Errorf("%s is not a sitemap (root element <%s>)", target, name)
+	}
+	sitemap.URL = target
+	return &sitemap, nil
+}
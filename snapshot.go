@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snapshot is a canonicalized response saved under a name: the status, the
+// selected headers and the body, with ignored JSON fields removed and keys
+// in a stable order. URLRedacted records that secrets were taken out of URL,
+// so it cannot be sent again as is.
+type snapshot struct {
+	Name        string            `json:"name"`
+	SavedAt     string            `json:"savedAt"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	URLRedacted bool              `json:"urlRedacted,omitempty"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Ignore      []string          `json:"ignore,omitempty"`
+	Body        interface{}       `json:"body,omitempty"`
+	Text        string            `json:"text,omitempty"`
+}
+
+// errSnapshotMismatch is returned by "snapshot check" when the live response
+// differs from the saved one.
+var errSnapshotMismatch = errors.New("response does not match snapshot")
+
+var snapshotName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// snapshotOptions holds the flags the snapshot command adds to the request
+// flags.
+type snapshotOptions struct {
+	dir        string
+	keepHeader stringList
+	ignore     stringList
+}
+
+func newSnapshotFlags() (*flag.FlagSet, *options, *snapshotOptions) {
+	fs, opts := newRequestFlags()
+	fs.Init("snapshot", flag.ExitOnError)
+	snap := &snapshotOptions{}
+	fs.StringVar(&snap.dir, "snapshot-dir", filepath.Join(".rest-blazar", "snapshots"), "Directory snapshots are stored in")
+	fs.Var(&snap.keepHeader, "keep-header", "Also record and compare this response `header` (repeatable; Content-Type always is)")
+	fs.Var(&snap.ignore, "ignore", "Leave this JSON body `path` out of the comparison, * matching any key or index (e.g. items.*.updatedAt; repeatable)")
+	return fs, opts, snap
+}
+
+// runSnapshot implements "rest-blazar snapshot save|check NAME [flags]".
+func runSnapshot(ctx context.Context, args []string) {
+	fs, opts, snap := newSnapshotFlags()
+	fs.Usage = func() { printCommandHelp(fs.Output(), snapshotCommand) }
+	if len(args) == 0 || args[0] != "save" && args[0] != "check" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	action := args[0]
+
+	// the name may come before or after the flags
+	var name string
+	if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+		name = args[1]
+		fs.Parse(args[2:])
+	} else {
+		fs.Parse(args[1:])
+		name = fs.Arg(0)
+	}
+
+	loc, err := newLocalizer(opts.locale)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	out, restoreConsole := setupConsole(os.Stdout)
+
+	switch {
+	case !snapshotName.MatchString(name):
+		err = &flagError{"snapshot", fmt.Sprintf("%q is not a valid snapshot name; use letters, digits, '.', '_' and '-'", name)}
+	case action == "save":
+		err = saveSnapshot(ctx, opts, snap, name, loc, out)
+	default:
+		methodSet := false
+		fs.Visit(func(f *flag.Flag) { methodSet = methodSet || f.Name == "method" })
+		err = checkSnapshot(ctx, opts, snap, name, methodSet, loc, out)
+	}
+	code := exitCode(out, loc, opts.output, err)
+	restoreConsole()
+	os.Exit(code)
+}
+
+func snapshotPath(snap *snapshotOptions, name string) string {
+	return filepath.Join(snap.dir, name+".json")
+}
+
+// fetchSnapshot sends the request described by opts and canonicalizes the
+// response.
+func fetchSnapshot(ctx context.Context, opts *options, loc *localizer, w io.Writer, name string, headers, ignore []string) (*snapshot, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if err := resolveCredentials(&opts.username, &opts.password, !opts.nonInteractive); err != nil {
+		return nil, err
+	}
+	if opts.script != "" {
+		return nil, &flagError{"script", "has no effect with snapshot; save one snapshot per request"}
+	}
+	sess, err := newSession(opts, loc, w, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.close()
+
+	req, resp, err := exchange(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &snapshot{
+		Name:        name,
+		SavedAt:     time.Now().UTC().Format(time.RFC3339),
+		Method:      req.Method,
+		URL:         redactURL(req.URL),
+		URLRedacted: redactURL(req.URL) != req.URL.String(),
+		Status:      resp.StatusCode,
+		Headers:     make(map[string]string),
+		Ignore:      ignore,
+	}
+	for _, h := range append([]string{"Content-Type"}, headers...) {
+		if values := resp.Header.Values(h); len(values) > 0 {
+			s.Headers[http.CanonicalHeaderKey(strings.TrimSpace(h))] = strings.Join(values, ", ")
+		}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(resp.body, &doc); err == nil {
+		for _, rule := range ignore {
+			dropPath(doc, strings.Split(rule, "."))
+		}
+		s.Body = doc
+	} else {
+		s.Text = string(resp.body)
+	}
+	return s, nil
+}
+
+func saveSnapshot(ctx context.Context, opts *options, snap *snapshotOptions, name string, loc *localizer, w io.Writer) error {
+	s, err := fetchSnapshot(ctx, opts, loc, w, name, snap.keepHeader, snap.ignore)
+	if err != nil {
+		return err
+	}
+
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	path := snapshotPath(snap, name)
+	if err := os.MkdirAll(snap.dir, 0755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(buf.String()), 0644); err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+
+	if opts.output == "json" {
+		printSnapshotResult(w, name, path, nil)
+	} else {
+		fmt.Fprintln(w, loc.T("Saved snapshot %s (%d) to %s", name, s.Status, path))
+	}
+	return nil
+}
+
+// checkSnapshot requests the endpoint again, with the method and URL of the
+// snapshot unless the flags give others, and compares the responses using
+// the snapshot's headers and ignore rules plus any given now.
+func checkSnapshot(ctx context.Context, opts *options, snap *snapshotOptions, name string, methodSet bool, loc *localizer, w io.Writer) error {
+	path := snapshotPath(snap, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+	var saved snapshot
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("reading snapshot %s: %v", path, err)
+	}
+	if opts.url == "" {
+		if saved.URLRedacted {
+			return &flagError{"url", fmt.Sprintf("is required: secrets were redacted from the URL saved in %s", path)}
+		}
+		opts.url = saved.URL
+		if !methodSet {
+			opts.method = saved.Method
+		}
+	}
+
+	var headers []string
+	for h := range saved.Headers {
+		headers = append(headers, h)
+	}
+	headers = append(headers, snap.keepHeader...)
+	ignore := append(saved.Ignore[:len(saved.Ignore):len(saved.Ignore)], snap.ignore...)
+
+	live, err := fetchSnapshot(ctx, opts, loc, w, name, headers, ignore)
+	if err != nil {
+		return err
+	}
+	// rules given now apply to the saved body too
+	for _, rule := range snap.ignore {
+		dropPath(saved.Body, strings.Split(rule, "."))
+	}
+
+	diffs := diffSnapshots(&saved, live)
+	if opts.output == "json" {
+		printSnapshotResult(w, name, path, diffs)
+	} else if len(diffs) == 0 {
+		fmt.Fprintln(w, loc.T("Snapshot %s matches", name))
+	} else {
+		fmt.Fprintln(w, loc.T("Snapshot %s differs:", name))
+		for _, d := range diffs {
+			fmt.Fprintf(w, "  %s\n", d)
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w %s (%d differences)", errSnapshotMismatch, name, len(diffs))
+	}
+	return nil
+}
+
+func printSnapshotResult(w io.Writer, name, path string, diffs []string) {
+	result := map[string]interface{}{
+		"snapshot":    name,
+		"path":        path,
+		"match":       len(diffs) == 0,
+		"differences": diffs,
+	}
+	if diffs == nil {
+		result["differences"] = []string{}
+	}
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "Error marshaling JSON response: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, string(jsonData))
+}
+
+// diffSnapshots lists how live differs from saved, one line per difference.
+func diffSnapshots(saved, live *snapshot) []string {
+	var diffs []string
+	if saved.Status != live.Status {
+		diffs = append(diffs, fmt.Sprintf("status: expected %d, got %d", saved.Status, live.Status))
+	}
+
+	names := make([]string, 0, len(saved.Headers))
+	for h := range saved.Headers {
+		names = append(names, h)
+	}
+	sort.Strings(names)
+	for _, h := range names {
+		if got, ok := live.Headers[h]; !ok {
+			diffs = append(diffs, fmt.Sprintf("header %s: missing", h))
+		} else if got != saved.Headers[h] {
+			diffs = append(diffs, fmt.Sprintf("header %s: expected %q, got %q", h, saved.Headers[h], got))
+		}
+	}
+
+	if saved.Text != live.Text {
+		diffs = append(diffs, "body: text differs")
+	}
+	diffJSON("body", saved.Body, live.Body, &diffs)
+	return diffs
+}
+
+// diffJSON compares two decoded JSON values and appends a line for every
+// field that differs, naming it by its path.
+func diffJSON(path string, want, got interface{}, diffs *[]string) {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			wv, inWant := w[k]
+			gv, inGot := g[k]
+			switch {
+			case !inGot:
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: missing", path, k))
+			case !inWant:
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: unexpected field", path, k))
+			default:
+				diffJSON(path+"."+k, wv, gv, diffs)
+			}
+		}
+		return
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			break
+		}
+		if len(w) != len(g) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: expected %d items, got %d", path, len(w), len(g)))
+		}
+		for i := 0; i < len(w) && i < len(g); i++ {
+			diffJSON(path+"."+strconv.Itoa(i), w[i], g[i], diffs)
+		}
+		return
+	}
+	if !reflect.DeepEqual(want, got) {
+		*diffs = append(*diffs, fmt.Sprintf("%s: expected %s, got %s", path, compactJSON(want), compactJSON(got)))
+	}
+}
+
+func compactJSON(v interface{}) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(out)
+}
+
+// dropPath removes the values at a dot separated path from a decoded JSON
+// document. A "*" segment matches every key or index; ignored array
+// elements become null so the indexes of the others stay the same.
+func dropPath(node interface{}, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if keys[0] != "*" && keys[0] != k {
+				continue
+			}
+			if len(keys) == 1 {
+				delete(n, k)
+			} else {
+				dropPath(v, keys[1:])
+			}
+		}
+	case []interface{}:
+		for i, v := range n {
+			if keys[0] != "*" && keys[0] != strconv.Itoa(i) {
+				continue
+			}
+			if len(keys) == 1 {
+				n[i] = nil
+			} else {
+				dropPath(v, keys[1:])
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("decoding %s: %v", s, err)
+	}
+	return v
+}
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		want, got string
+		diffs     []string
+	}{
+		{`{"a":1,"b":[1,2]}`, `{"b":[1,2],"a":1}`, nil},
+		{`{"a":1}`, `{"a":2}`, []string{"body.a: expected 1, got 2"}},
+		{`{"a":1,"b":2}`, `{"a":1}`, []string{"body.b: missing"}},
+		{`{"a":1}`, `{"a":1,"c":3}`, []string{"body.c: unexpected field"}},
+		{`{"a":{"b":"x"}}`, `{"a":{"b":"y"}}`, []string{`body.a.b: expected "x", got "y"`}},
+		{`[1,2,3]`, `[1,5]`, []string{"body: expected 3 items, got 2", "body.1: expected 2, got 5"}},
+		{`{"a":[{"id":1}]}`, `{"a":[{"id":2}]}`, []string{"body.a.0.id: expected 1, got 2"}},
+		{`{"a":{"b":1}}`, `{"a":[1]}`, []string{`body.a: expected {"b":1}, got [1]`}},
+		{`{"a":null}`, `{"a":false}`, []string{"body.a: expected null, got false"}},
+	}
+	for _, tt := range tests {
+		var diffs []string
+		diffJSON("body", decodeJSON(t, tt.want), decodeJSON(t, tt.got), &diffs)
+		if !reflect.DeepEqual(diffs, tt.diffs) {
+			t.Errorf("diffJSON(%s, %s) = %q, want %q", tt.want, tt.got, diffs, tt.diffs)
+		}
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	saved := &snapshot{
+		Status:  200,
+		Headers: map[string]string{"Content-Type": "application/json", "Etag": `"1"`},
+		Body:    map[string]interface{}{"id": 1.0},
+	}
+	tests := []struct {
+		name  string
+		live  snapshot
+		diffs []string
+	}{
+		{"equal", snapshot{Status: 200, Headers: map[string]string{"Content-Type": "application/json", "Etag": `"1"`, "X-Extra": "ignored"}, Body: map[string]interface{}{"id": 1.0}}, nil},
+		{"status", snapshot{Status: 404, Headers: saved.Headers, Body: saved.Body}, []string{"status: expected 200, got 404"}},
+		{"headers", snapshot{Status: 200, Headers: map[string]string{"Content-Type": "text/plain"}, Body: saved.Body}, []string{
+			`header Content-Type: expected "application/json", got "text/plain"`,
+			"header Etag: missing",
+		}},
+		{"body", snapshot{Status: 200, Headers: saved.Headers, Body: map[string]interface{}{"id": 2.0}}, []string{"body.id: expected 1, got 2"}},
+		{"text instead of JSON", snapshot{Status: 200, Headers: saved.Headers, Text: "oops"}, []string{"body: text differs", `body: expected {"id":1}, got null`}},
+	}
+	for _, tt := range tests {
+		if diffs := diffSnapshots(saved, &tt.live); !reflect.DeepEqual(diffs, tt.diffs) {
+			t.Errorf("%s: got %q, want %q", tt.name, diffs, tt.diffs)
+		}
+	}
+}
+
+func TestDropPath(t *testing.T) {
+	tests := []struct {
+		doc, path, want string
+	}{
+		{`{"a":1,"b":2}`, "a", `{"b":2}`},
+		{`{"a":{"b":1,"c":2}}`, "a.b", `{"a":{"c":2}}`},
+		{`{"a":1}`, "missing.path", `{"a":1}`},
+		{`{"items":[{"id":1,"at":"x"},{"id":2,"at":"y"}]}`, "items.*.at", `{"items":[{"id":1},{"id":2}]}`},
+		{`{"items":[1,2,3]}`, "items.1", `{"items":[1,null,3]}`},
+		{`{"items":[1,2]}`, "items.*", `{"items":[null,null]}`},
+		{`{"a":{"at":1},"b":{"at":2,"keep":3}}`, "*.at", `{"a":{},"b":{"keep":3}}`},
+		{`{"a":"text"}`, "a.b", `{"a":"text"}`},
+	}
+	for _, tt := range tests {
+		doc := decodeJSON(t, tt.doc)
+		dropPath(doc, strings.Split(tt.path, "."))
+		if got := compactJSON(doc); got != tt.want {
+			t.Errorf("dropPath(%s, %q) = %s, want %s", tt.doc, tt.path, got, tt.want)
+		}
+	}
+}
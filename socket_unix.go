@@ -0,0 +1,40 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenPrivate listens on a unix socket that only the current user can
+// connect to. The umask applies while the socket file is created, so there
+// is no moment in which it is open to others.
+func listenPrivate(path string) (net.Listener, error) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
+
+// checkOwned verifies that path belongs to the current user and that
+// nobody else has access to it, so a socket or directory planted by another
+// user is never trusted.
+func checkOwned(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by another user", path)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s is accessible to other users (mode %v)", path, info.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package main
+
+import "net"
+
+// listenPrivate listens on a unix socket. The default socket lives in the
+// per-user temporary directory, which other users cannot reach.
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
+
+// checkOwned is a no-op on Windows, where the directory ACLs keep other
+// users out.
+func checkOwned(path string) error {
+	return nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version is the release this binary was built from, set at build time with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// updatePublicKey is the base64 ed25519 key that signs checksums.txt of each
+// release, set at build time with -ldflags "-X main.updatePublicKey=...".
+// When empty, update refuses to install unless -insecure-skip-signature is
+// given, and then verifies against the checksums only.
+var updatePublicKey = ""
+
+const releasesURL = "https://api.github.com/repos/admjkv/rest-blazar/releases/latest"
+
+// githubRelease is the subset of the GitHub release API used by update.
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r *githubRelease) assetURL(name string) string {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// updateOptions holds the flags of the update command.
+type updateOptions struct {
+	check         bool
+	force         bool
+	timeout       int
+	skipSignature bool
+}
+
+func newUpdateFlags() (*flag.FlagSet, *updateOptions) {
+	opts := &updateOptions{}
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	fs.BoolVar(&opts.check, "check", false, "Only report whether a newer release is available")
+	fs.BoolVar(&opts.force, "force", false, "Install the latest release even if it is not newer")
+	fs.IntVar(&opts.timeout, "timeout", 60, "Timeout in seconds")
+	fs.BoolVar(&opts.skipSignature, "insecure-skip-signature", false, "Install without a signature check when the build carries no signing key")
+	return fs, opts
+}
+
+// runUpdate implements "rest-blazar update [flags]".
+func runUpdate(ctx context.Context, args []string) {
+	fs, opts := newUpdateFlags()
+	fs.Usage = func() { printCommandHelp(fs.Output(), updateCommand) }
+	fs.Parse(args)
+
+	client := &http.Client{Timeout: time.Duration(opts.timeout) * time.Second}
+
+	var release githubRelease
+	data, err := fetchSiteFile(ctx, client, releasesURL)
+	if err == nil {
+		err = json.Unmarshal(data, &release)
+	}
+	if err != nil {
+		fmt.Printf("Error checking latest release: %v\n", err)
+		os.Exit(1)
+	}
+
+	newer := compareVersions(release.TagName, version) > 0
+	fmt.Printf("Current version: %s\n", version)
+	fmt.Printf("Latest release:  %s\n", release.TagName)
+	if opts.check {
+		if newer {
+			fmt.Println("An update is available; run \"rest-blazar update\" to install it.")
+		}
+		return
+	}
+	if !newer && !opts.force {
+		fmt.Println("Already up to date.")
+		return
+	}
+
+	if updatePublicKey == "" && !opts.skipSignature {
+		fmt.Println("Error: this build has no signing key to verify the release with; use -insecure-skip-signature to install it on the checksum alone")
+		os.Exit(1)
+	}
+
+	asset := fmt.Sprintf("rest-blazar_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		asset += ".exe"
+	}
+	binURL := release.assetURL(asset)
+	sumsURL := release.assetURL("checksums.txt")
+	if binURL == "" || sumsURL == "" {
+		fmt.Printf("Error: release %s has no %s or checksums.txt\n", release.TagName, asset)
+		os.Exit(1)
+	}
+
+	sums, err := fetchSiteFile(ctx, client, sumsURL)
+	if err != nil {
+		fmt.Printf("Error downloading checksums: %v\n", err)
+		os.Exit(1)
+	}
+	if updatePublicKey != "" {
+		sigURL := release.assetURL("checksums.txt.sig")
+		if sigURL == "" {
+			fmt.Printf("Error: release %s is not signed\n", release.TagName)
+			os.Exit(1)
+		}
+		sig, err := fetchSiteFile(ctx, client, sigURL)
+		if err == nil {
+			err = verifySignature(sums, sig)
+		}
+		if err != nil {
+			fmt.Printf("Error verifying signature: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		fmt.Println("Warning: no signing key built in, verifying checksum only")
+	}
+
+	fmt.Printf("Downloading %s...\n", asset)
+	bin, err := fetchSiteFile(ctx, client, binURL)
+	if err != nil {
+		fmt.Printf("Error downloading release: %v\n", err)
+		os.Exit(1)
+	}
+	if err := verifyChecksum(bin, sums, asset); err != nil {
+		fmt.Printf("Error verifying checksum: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := replaceExecutable(bin); err != nil {
+		fmt.Printf("Error installing update: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Updated to %s\n", release.TagName)
+}
+
+// verifySignature checks a base64 ed25519 signature of data against
+// updatePublicKey.
+func verifySignature(data, sig []byte) error {
+	key, err := base64.StdEncoding.DecodeString(updatePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid built-in public key")
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("decoding signature: %v", err)
+	}
+	if !ed25519.Verify(key, data, rawSig) {
+		return fmt.Errorf("signature does not match checksums.txt")
+	}
+	return nil
+}
+
+// verifyChecksum looks up name in a sha256sum style checksums file and
+// compares it with the digest of data.
+func verifyChecksum(data, sums []byte, name string) error {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, fields[0]) {
+			return fmt.Errorf("sha256 %s does not match expected %s", got, fields[0])
+		}
+		return nil
+	}
+	return fmt.Errorf("no checksum listed for %s", name)
+}
+
+// replaceExecutable swaps the running binary for bin. The new file is
+// written next to the old one so the final rename stays on one filesystem.
+func replaceExecutable(bin []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	tmp := exe + ".new"
+	if err := os.WriteFile(tmp, bin, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Windows cannot overwrite a running executable, but it can rename it.
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Rename(old, exe)
+		os.Remove(tmp)
+		return err
+	}
+	if runtime.GOOS != "windows" {
+		os.Remove(old)
+	}
+	return nil
+}
+
+// compareVersions compares two "vMAJOR.MINOR.PATCH" versions, returning
+// -1, 0 or 1. Anything that does not parse, such as "dev", sorts first.
+func compareVersions(a, b string) int {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
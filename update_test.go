@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		v    string
+		want [3]int
+		ok   bool
+	}{
+		{"v1.2.3", [3]int{1, 2, 3}, true},
+		{"1.2.3", [3]int{1, 2, 3}, true},
+		{"v1.2", [3]int{1, 2, 0}, true},
+		{"v2", [3]int{2, 0, 0}, true},
+		{"v1.2.3-rc.1", [3]int{1, 2, 3}, true},
+		{"v1.2.3+build.7", [3]int{1, 2, 3}, true},
+		{"dev", [3]int{}, false},
+		{"", [3]int{}, false},
+		{"v1.2.3.4", [3]int{}, false},
+		{"v1.x.3", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.v)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseVersion(%q) = %v, %v; want %v, %v", tt.v, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.2.4", "v1.2.3", 1},
+		{"v1.10.0", "v1.9.9", 1},
+		{"v1.9.9", "v2.0.0", -1},
+		{"v1.2", "v1.2.0", 0},
+		{"v1.0.0", "dev", 1},
+		{"dev", "v0.0.1", -1},
+		{"dev", "snapshot", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	bin := []byte("binary")
+	digest := sha256.Sum256(bin)
+	sum := hex.EncodeToString(digest[:])
+	sums := []byte("0000  rest-blazar_darwin_arm64\n" +
+		sum + "  rest-blazar_linux_amd64\n" +
+		sum + " *rest-blazar_windows_amd64.exe\n")
+
+	tests := []struct {
+		name string
+		data []byte
+		err  bool
+	}{
+		{"rest-blazar_linux_amd64", bin, false},
+		{"rest-blazar_windows_amd64.exe", bin, false},
+		{"rest-blazar_linux_amd64", []byte("tampered"), true},
+		{"rest-blazar_darwin_arm64", bin, true},
+		{"rest-blazar_linux_arm64", bin, true},
+	}
+	for _, tt := range tests {
+		if err := verifyChecksum(tt.data, sums, tt.name); (err != nil) != tt.err {
+			t.Errorf("verifyChecksum(%s) error = %v, want error %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(key string) { updatePublicKey = key }(updatePublicKey)
+	updatePublicKey = base64.StdEncoding.EncodeToString(pub)
+
+	sums := []byte("abc  rest-blazar_linux_amd64\n")
+	sig := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, sums)) + "\n")
+	if err := verifySignature(sums, sig); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	if err := verifySignature([]byte("abd  rest-blazar_linux_amd64\n"), sig); err == nil {
+		t.Errorf("signature of other checksums was accepted")
+	}
+	if err := verifySignature(sums, []byte("not base64!")); err == nil {
+		t.Errorf("malformed signature was accepted")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// flagError is a problem with the value of one flag or with how it is
+// combined with another.
+type flagError struct {
+	flag string
+	msg  string
+}
+
+func (e *flagError) Error() string {
+	return "-" + e.flag + ": " + e.msg
+}
+
+// validationError lists every problem found by options.validate.
+type validationError []error
+
+func (e validationError) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = "  " + err.Error()
+	}
+	return "invalid flags:\n" + strings.Join(lines, "\n")
+}
+
+var outputFormats = []string{"pretty", "json", "headers-only", "body-only"}
+
+// validate checks the flags for values and combinations that would
+// otherwise be ignored or fail deep inside the request, and reports all of
+// them at once.
+func (o *options) validate() error {
+	var errs validationError
+	add := func(flag, format string, args ...interface{}) {
+		errs = append(errs, &flagError{flag, fmt.Sprintf(format, args...)})
+	}
+
+	if o.url == "" && o.script == "" {
+		add("url", "a URL is required (or use -script to run a request file)")
+	}
+	if o.url != "" && o.script != "" {
+		add("script", "cannot be combined with -url; the script lists its own URLs")
+	}
+	if o.script != "" {
+		for _, f := range []struct{ name, value string }{
+			{"body", o.body}, {"body-file", o.bodyFile}, {"json", o.jsonData},
+			{"form", o.formData}, {"follow-rel", o.followRel}, {"copy-field", o.copyField},
+		} {
+			if f.value != "" {
+				add(f.name, "has no effect with -script; put bodies in the script instead")
+			}
+		}
+	}
+
+	// only one body source is used, so more than one is a mistake
+	var sources []string
+	for _, f := range []struct{ name, value string }{
+		{"body", o.body}, {"body-file", o.bodyFile}, {"json", o.jsonData}, {"form", o.formData},
+	} {
+		if f.value != "" {
+			sources = append(sources, "-"+f.name)
+		}
+	}
+	if len(sources) > 1 {
+		add(strings.TrimPrefix(sources[1], "-"), "cannot be combined with %s; choose one way to give the body", sources[0])
+	}
+
+	method := strings.ToUpper(o.method)
+	if !isToken(o.method) {
+		add("method", "%q is not a valid HTTP method", o.method)
+	} else if len(sources) > 0 && (method == http.MethodGet || method == http.MethodHead) {
+		add("method", "%s requests do not send a body; use -method POST, PUT or PATCH with %s", method, sources[0])
+	}
+
+	// with -failover the URL may be just a path to request from every base
+	relative := o.failover != "" && strings.HasPrefix(o.url, "/")
+	if o.url != "" && !relative && !strings.Contains(o.url, "{{") {
+		if u, err := url.Parse(o.url); err != nil {
+			add("url", "%v", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			if u.Scheme == "" {
+				add("url", "%q has no scheme; did you mean https://%s?", o.url, o.url)
+			} else {
+				add("url", "unsupported scheme %q; use http or https", u.Scheme)
+			}
+		} else if u.Host == "" {
+			add("url", "%q has no host", o.url)
+		}
+	}
+
+	for _, h := range headerLines(o.headers) {
+		name, _, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		switch {
+		case !ok:
+			add("headers", "%q is not in \"Key: Value\" form (commas and newlines both separate headers)", h)
+		case !isToken(name):
+			add("headers", "%q is not a valid header name", name)
+		}
+	}
+
+	checkPairs := func(flag, value string) {
+		if value == "" {
+			return
+		}
+		for _, pair := range strings.Split(value, ",") {
+			if k, _, ok := strings.Cut(pair, "="); !ok || strings.TrimSpace(k) == "" {
+				add(flag, "%q is not a key=value pair", pair)
+			}
+		}
+	}
+	checkPairs("json", o.jsonData)
+	checkPairs("form", o.formData)
+	checkPairs("vars", o.vars)
+
+	if !contains(outputFormats, o.output) {
+		add("output", "unknown format %q; use one of %s", o.output, strings.Join(outputFormats, ", "))
+	}
+	if o.timeout < 0 {
+		add("timeout", "must not be negative (0 disables the timeout)")
+	}
+	if o.retries < 0 {
+		add("retries", "must not be negative")
+	}
+	if o.retryDelay < 0 {
+		add("retry-delay", "must not be negative")
+	}
+	if o.followRel != "" && strings.ContainsAny(o.followRel, " ,;") {
+		add("follow-rel", "takes a single relation type such as next, not %q", o.followRel)
+	}
+
+	if o.retryFor < 0 {
+		add("retry-for", "must not be negative (0 disables the limit)")
+	}
+	o.retryCond = nil
+	if o.retryIf != "" {
+		cond, err := parseCondition(o.retryIf)
+		if err != nil {
+			add("retry-if", "%v", err)
+		} else {
+			o.retryCond = cond
+		}
+		if o.retries == 0 && o.retryFor == 0 {
+			add("retry-if", "needs -retries or -retry-for to stop retrying")
+		}
+		if o.retryDelay == 0 {
+			add("retry-delay", "must be at least 1 with -retry-if")
+		}
+	}
+
+	if o.script != "" && o.pollLocation {
+		add("poll-location", "has no effect with -script")
+	}
+	o.failoverBases = nil
+	if o.failover != "" {
+		if o.script != "" {
+			add("failover", "has no effect with -script")
+		}
+		bases, err := parseFailover(o.failover)
+		switch {
+		case err != nil:
+			add("failover", "%v", err)
+		case len(bases) == 0:
+			add("failover", "lists no base URLs")
+		default:
+			o.failoverBases = bases
+		}
+	}
+	if !o.pollLocation && (o.pollURLField != "" || o.pollUntil != "") {
+		add("poll-location", "is required by -poll-url-field and -poll-until")
+	}
+	if o.pollInterval < 1 {
+		add("poll-interval", "must be at least 1")
+	}
+	if o.pollBackoff < 1 {
+		add("poll-backoff", "must be at least 1 (1 polls at a fixed interval)")
+	}
+	if o.pollTimeout < 0 {
+		add("poll-timeout", "must not be negative (0 disables the limit)")
+	}
+	o.pollCond = nil
+	if o.pollUntil != "" {
+		if cond, err := parseCondition(o.pollUntil); err != nil {
+			add("poll-until", "%v", err)
+		} else {
+			o.pollCond = cond
+		}
+	}
+
+	o.headerRules = nil
+	for _, text := range o.expectHeaders {
+		rule, err := parseHeaderRule(text)
+		if err != nil {
+			add("expect-header", "%v", err)
+			continue
+		}
+		o.headerRules = append(o.headerRules, rule)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// isToken reports whether s is an RFC 9110 token, as required for methods
+// and header names.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c >= 0x7f || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
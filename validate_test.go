@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		flags []string // offending flags, in the order they are reported
+	}{
+		{"plain GET", []string{"-url", "https://api.example.com/items"}, nil},
+		{"no URL", nil, []string{"url"}},
+		{"URL and script", []string{"-url", "https://x/", "-script", "steps.txt"}, []string{"script"}},
+		{"json and form", []string{"-url", "https://x/", "-method", "POST", "-json", "a=1", "-form", "b=2"}, []string{"form"}},
+		{"body with GET", []string{"-url", "https://x/", "-body", "{}"}, []string{"method"}},
+		{"body with POST", []string{"-url", "https://x/", "-method", "post", "-body", "{}"}, nil},
+		{"invalid method", []string{"-url", "https://x/", "-method", "GE T"}, []string{"method"}},
+		{"negative timeout", []string{"-url", "https://x/", "-timeout", "-1"}, []string{"timeout"}},
+		{"malformed header", []string{"-url", "https://x/", "-headers", "Accept"}, []string{"headers"}},
+		{"invalid header name", []string{"-url", "https://x/", "-headers", "Bad Name: 1"}, []string{"headers"}},
+		{"headers split on commas and newlines", []string{"-url", "https://x/", "-headers", "A: 1,B: 2\nC: 3"}, nil},
+		{"scheme-less URL", []string{"-url", "api.example.com/items"}, []string{"url"}},
+		{"unsupported scheme", []string{"-url", "ftp://x/"}, []string{"url"}},
+		{"variable URL is not parsed", []string{"-url", "{{base}}/items"}, nil},
+		{"relative URL", []string{"-url", "/items"}, []string{"url"}},
+		{"relative URL with failover", []string{"-url", "/items", "-failover", "https://a/,https://b/"}, nil},
+		{"bad failover base", []string{"-url", "/items", "-failover", "ftp://a/"}, []string{"failover"}},
+		{"bad json pair", []string{"-url", "https://x/", "-method", "POST", "-json", "a"}, []string{"json"}},
+		{"unknown output", []string{"-url", "https://x/", "-output", "xml"}, []string{"output"}},
+		{"retry-if without limit", []string{"-url", "https://x/", "-retry-if", "status==409", "-retry-for", "0"}, []string{"retry-if"}},
+		{"bad retry-if", []string{"-url", "https://x/", "-retry-if", "status =="}, []string{"retry-if"}},
+		{"retry-if without delay", []string{"-url", "https://x/", "-retry-if", "status==409", "-retries", "3", "-retry-delay", "0"}, []string{"retry-delay"}},
+		{"poll-until without poll-location", []string{"-url", "https://x/", "-poll-until", "status==200"}, []string{"poll-location"}},
+		{"zero poll interval", []string{"-url", "https://x/", "-poll-location", "-poll-interval", "0"}, []string{"poll-interval"}},
+		{"malformed expect-header", []string{"-url", "https://x/", "-expect-header", "!ETag: 1"}, []string{"expect-header"}},
+		{"several problems", []string{"-url", "x", "-timeout", "-1", "-retries", "-1"}, []string{"url", "timeout", "retries"}},
+	}
+	for _, tt := range tests {
+		fs, opts := newRequestFlags()
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("%s: parsing flags: %v", tt.name, err)
+		}
+		var flags []string
+		if err := opts.validate(); err != nil {
+			var errs validationError
+			if !errors.As(err, &errs) {
+				t.Fatalf("%s: validate returned %T, want validationError", tt.name, err)
+			}
+			for _, e := range errs {
+				flags = append(flags, e.(*flagError).flag)
+			}
+		}
+		if !reflect.DeepEqual(flags, tt.flags) {
+			t.Errorf("%s: offending flags %v, want %v", tt.name, flags, tt.flags)
+		}
+	}
+}